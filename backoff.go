@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// minJitteredInterval is the shortest interval -jitter can produce
+const minJitteredInterval = 100 * time.Millisecond
+
+// backoffDelay returns the wait before the next check after the given number
+// of consecutive failures, doubling the base interval for each of them up to
+// maxInterval
+func backoffDelay(base, maxInterval time.Duration, failures int) time.Duration {
+	delay := base
+	for range failures {
+		if delay >= maxInterval/2 {
+			return max(maxInterval, base)
+		}
+		delay *= 2
+	}
+	return delay
+}
+
+// nextInterval returns how long to wait before checking targets again. Only
+// a round where every target failed is backed off, so one healthy target
+// keeps the base interval.
+func nextInterval(targets []*target, base, maxInterval time.Duration) time.Duration {
+	failures := targets[0].consecutiveFailures
+	for _, t := range targets[1:] {
+		failures = min(failures, t.consecutiveFailures)
+	}
+	return backoffDelay(base, maxInterval, failures)
+}
+
+// jitter randomizes an interval by up to ±pct percent, never going below
+// minJitteredInterval. A pct of zero returns the interval unchanged.
+func jitter(rng *rand.Rand, interval time.Duration, pct float64) time.Duration {
+	if pct <= 0 {
+		return interval
+	}
+	offset := (rng.Float64()*2 - 1) * pct / 100
+	return max(interval+time.Duration(float64(interval)*offset), minJitteredInterval)
+}
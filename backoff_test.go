@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		base, max time.Duration
+		failures  int
+		want      time.Duration
+	}{
+		{2 * time.Second, time.Minute, 0, 2 * time.Second},
+		{2 * time.Second, time.Minute, 1, 4 * time.Second},
+		{2 * time.Second, time.Minute, 2, 8 * time.Second},
+		{2 * time.Second, time.Minute, 3, 16 * time.Second},
+		{2 * time.Second, time.Minute, 4, 32 * time.Second},
+		{2 * time.Second, time.Minute, 5, time.Minute},
+		{2 * time.Second, time.Minute, 1000, time.Minute},
+		{10 * time.Second, 40 * time.Second, 2, 40 * time.Second},
+		{10 * time.Second, 30 * time.Second, 2, 30 * time.Second},
+		// A max below the base never shortens the interval
+		{time.Minute, 30 * time.Second, 3, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.base, tt.max, tt.failures); got != tt.want {
+			t.Errorf("backoffDelay(%s, %s, %d) = %s, want %s", tt.base, tt.max, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestNextIntervalBacksOffOnlyWhenAllFail(t *testing.T) {
+	down, up := newTestTarget(), newTestTarget()
+	down.consecutiveFailures = 3
+	if got := nextInterval([]*target{down, up}, 2*time.Second, time.Minute); got != 2*time.Second {
+		t.Errorf("with one healthy target the interval = %s, want 2s", got)
+	}
+	up.consecutiveFailures = 1
+	if got := nextInterval([]*target{down, up}, 2*time.Second, time.Minute); got != 4*time.Second {
+		t.Errorf("backed off to %s, want 4s from the fewest failures", got)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// burstStats summarizes a -burst of probes sent in one tick
+type burstStats struct {
+	Sent int
+	Lost int
+
+	// Latencies of the probes that got through, zero when none did
+	Min time.Duration
+	Avg time.Duration
+	Max time.Duration
+}
+
+// loss returns the percentage of probes lost
+func (b burstStats) loss() float64 {
+	return lossPercent(b.Lost, b.Sent)
+}
+
+func lossPercent(lost, sent int) float64 {
+	if sent == 0 {
+		return 0
+	}
+	return 100 * float64(lost) / float64(sent)
+}
+
+// burstProber sends several probes each tick, spaced a little apart, so
+// the share that fail gives a ping-like packet loss
+type burstProber struct {
+	Prober
+	count   int
+	spacing time.Duration // between the starts of consecutive probes
+
+	// budget bounds the whole burst, zero leaves it unbounded
+	budget time.Duration
+}
+
+// Probe returns the first successful probe with the burst's average
+// latency, or the last failed one when every probe was lost. Either way
+// Burst holds the loss and latency spread.
+func (p *burstProber) Probe(ctx context.Context) checkResult {
+	if p.budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.budget)
+		defer cancel()
+	}
+	results := make([]checkResult, p.count)
+	var wg sync.WaitGroup
+	for i := range results {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				results[i] = checkResult{Time: time.Now(), Err: ctx.Err(), Reason: failureReason(ctx.Err())}
+				continue
+			case <-time.After(p.spacing):
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.Prober.Probe(ctx)
+		}()
+	}
+	wg.Wait()
+
+	stats := burstStats{Sent: p.count}
+	var total time.Duration
+	first := -1
+	for i, r := range results {
+		if !r.Connected {
+			stats.Lost++
+			continue
+		}
+		if first < 0 {
+			first = i
+			stats.Min = r.Latency
+		}
+		stats.Min = min(stats.Min, r.Latency)
+		stats.Max = max(stats.Max, r.Latency)
+		total += r.Latency
+	}
+	if first < 0 {
+		result := results[len(results)-1]
+		result.Time = results[0].Time
+		result.Burst = &stats
+		return result
+	}
+	stats.Avg = total / time.Duration(stats.Sent-stats.Lost)
+	result := results[first]
+	result.Time = results[0].Time
+	result.Latency = stats.Avg
+	result.Burst = &stats
+	return result
+}
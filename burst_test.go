@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedProber answers its probes in turn from a script of latencies,
+// where zero is a lost probe
+type scriptedProber struct {
+	script []time.Duration
+	calls  atomic.Int32
+}
+
+func (p *scriptedProber) Probe(ctx context.Context) checkResult {
+	i := int(p.calls.Add(1)) - 1
+	r := checkResult{Time: time.Now()}
+	if l := p.script[i%len(p.script)]; l > 0 {
+		r.Connected, r.Latency = true, l
+	} else {
+		r.Reason = "TIMEOUT"
+	}
+	return r
+}
+
+func TestBurst(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name          string
+		script        []time.Duration
+		connected     bool
+		lost          int
+		loss          float64
+		min, avg, max time.Duration
+	}{
+		{"no loss", []time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms}, true, 0, 0, 10 * ms, 25 * ms, 40 * ms},
+		{"some lost", []time.Duration{0, 30 * ms, 0, 10 * ms, 20 * ms, 0, 0, 20 * ms, 0, 0}, true, 6, 60, 10 * ms, 20 * ms, 30 * ms},
+		{"one through", []time.Duration{0, 0, 0, 15 * ms}, true, 3, 75, 15 * ms, 15 * ms, 15 * ms},
+		{"all lost", []time.Duration{0, 0, 0}, false, 3, 100, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &burstProber{Prober: &scriptedProber{script: tt.script}, count: len(tt.script), spacing: time.Millisecond}
+			r := p.Probe(context.Background())
+			b := r.Burst
+			if b == nil {
+				t.Fatal("no burst stats")
+			}
+			if r.Connected != tt.connected || b.Sent != len(tt.script) || b.Lost != tt.lost || b.loss() != tt.loss {
+				t.Errorf("got connected %v, %d of %d lost (%g%%), want %v, %d of %d (%g%%)",
+					r.Connected, b.Lost, b.Sent, b.loss(), tt.connected, tt.lost, len(tt.script), tt.loss)
+			}
+			if b.Min != tt.min || b.Avg != tt.avg || b.Max != tt.max {
+				t.Errorf("min/avg/max = %s/%s/%s, want %s/%s/%s", b.Min, b.Avg, b.Max, tt.min, tt.avg, tt.max)
+			}
+			if r.Connected && r.Latency != tt.avg {
+				t.Errorf("latency = %s, want the burst average %s", r.Latency, tt.avg)
+			}
+		})
+	}
+}
+
+func TestBurstBudget(t *testing.T) {
+	// Probes go out at 0, 100ms and 200ms, the budget runs out before the
+	// rest are sent
+	prober := &scriptedProber{script: []time.Duration{time.Millisecond}}
+	p := &burstProber{Prober: prober, count: 5, spacing: 100 * time.Millisecond, budget: 250 * time.Millisecond}
+	r := p.Probe(context.Background())
+	if got := prober.calls.Load(); got != 3 {
+		t.Errorf("sent %d probes, want 3 within the budget", got)
+	}
+	if !r.Connected || r.Burst.Sent != 5 || r.Burst.Lost != 2 {
+		t.Errorf("got connected %v, %d of %d lost, want connected, 2 of 5", r.Connected, r.Burst.Lost, r.Burst.Sent)
+	}
+}
+
+func TestLossPercent(t *testing.T) {
+	tests := []struct {
+		lost, sent int
+		want       float64
+	}{
+		{0, 0, 0},
+		{0, 10, 0},
+		{1, 4, 25},
+		{1, 3, 100.0 / 3},
+		{5, 5, 100},
+	}
+	for _, tt := range tests {
+		if got := lossPercent(tt.lost, tt.sent); got != tt.want {
+			t.Errorf("lossPercent(%d, %d) = %g, want %g", tt.lost, tt.sent, got, tt.want)
+		}
+	}
+}
+
+func TestBurstLossAccumulates(t *testing.T) {
+	tg := newTestTarget()
+	bursts := []burstStats{{Sent: 10, Lost: 1}, {Sent: 10, Lost: 0}, {Sent: 10, Lost: 10}, {Sent: 10, Lost: 4}}
+	for i, b := range bursts {
+		now := testStart.Add(time.Duration(i) * time.Second)
+		tg.observe(checkResult{Time: now, Connected: b.Lost < b.Sent, Burst: &b}, now)
+	}
+	if tg.probesSent != 40 || tg.probesLost != 15 {
+		t.Fatalf("accumulated %d of %d lost, want 15 of 40", tg.probesLost, tg.probesSent)
+	}
+	if s := newTargetSummary(tg); s.PacketLossPct == nil || *s.PacketLossPct != 37.5 {
+		t.Errorf("summary packet loss = %v, want 37.5", s.PacketLossPct)
+	}
+}
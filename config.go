@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the options read from a -config file, keyed by flag name.
+// Repeatable flags such as url and header take a list.
+type Config map[string]any
+
+// listFlag is a repeatable flag whose values are kept as a list
+type listFlag interface {
+	flag.Value
+	Values() []string
+	Reset() // drops every value, so the next Set starts a new list
+}
+
+// configOnlyFlags control loading the config rather than being part of it
+var configOnlyFlags = []string{"config", "print-config"}
+
+// secretFlags hold credentials, which -print-config leaves out. The Slack
+// and Discord webhook URLs are credentials in themselves.
+var secretFlags = []string{"bearer", "basic-auth", "mqtt-password", "telegram-token", "pagerduty-key", "slack-webhook", "discord-webhook"}
+
+// redacted stands in for a secret in -print-config output
+const redacted = "<redacted>"
+
+// envPrefix starts the name of the environment variable for every flag
+const envPrefix = "NETWORKCHECK_"
+
+// envName returns the environment variable for a flag, e.g.
+// NETWORKCHECK_LATENCY_WARN for -latency-warn
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets every flag in fs that wasn't given on the command line from
+// its environment variable, if set. Options resolve as command-line flag,
+// then environment, then -config file, then built-in default.
+func applyEnv(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var problems []string
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || explicit[f.Name] {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			problems = append(problems, fmt.Sprintf("  %s: invalid value %q", envName(f.Name), value))
+		}
+	})
+	if len(problems) > 0 {
+		return errors.New("invalid environment:\n" + strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// loadConfig reads the YAML file at path
+func loadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// apply sets every flag in fs that wasn't given on the command line from
+// cfg, returning an error that lists every invalid field
+func (cfg Config) apply(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var problems []string
+	keys := make([]string, 0, len(cfg))
+	for key := range cfg {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		f := fs.Lookup(key)
+		if f == nil || slices.Contains(configOnlyFlags, key) {
+			problems = append(problems, fmt.Sprintf("  %s: unknown option", key))
+			continue
+		}
+		if explicit[key] || cfg[key] == nil {
+			continue
+		}
+		values := []any{cfg[key]}
+		if list, ok := cfg[key].([]any); ok {
+			if _, repeatable := f.Value.(listFlag); !repeatable {
+				problems = append(problems, fmt.Sprintf("  %s: takes a single value, not a list", key))
+				continue
+			}
+			values = list
+		}
+		for _, v := range values {
+			if err := fs.Set(key, fmt.Sprint(v)); err != nil {
+				problems = append(problems, fmt.Sprintf("  %s: invalid value %v", key, v))
+				break
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid config:\n" + strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// printConfig writes the effective value of every flag in fs as YAML, in a
+// form -config accepts once any credentials, shown as <redacted>, are
+// filled back in
+func printConfig(w io.Writer, fs *flag.FlagSet) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	fs.VisitAll(func(f *flag.Flag) {
+		if slices.Contains(configOnlyFlags, f.Name) {
+			return
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: f.Name}
+		var value *yaml.Node
+		if list, ok := f.Value.(listFlag); ok {
+			value = &yaml.Node{Kind: yaml.SequenceNode}
+			for _, v := range list.Values() {
+				value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: redact(f.Name, v)})
+			}
+		} else {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Value: f.Value.String()}
+			if value.Value == "" {
+				value.Style = yaml.DoubleQuotedStyle
+			} else {
+				value.Value = redact(f.Name, value.Value)
+			}
+		}
+		doc.Content = append(doc.Content, key, value)
+	})
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// redact hides the credentials in a flag value: all of a secretFlags value,
+// the value of a -header such as Authorization, and the userinfo and
+// secret query parameters of a URL
+func redact(flagName, value string) string {
+	if slices.Contains(secretFlags, flagName) {
+		return redacted
+	}
+	if flagName == "header" {
+		if key, _, ok := strings.Cut(value, ":"); ok && secretName(key) {
+			return key + ": " + redacted
+		}
+		return value
+	}
+	return redactURL(value)
+}
+
+// redactURL hides the userinfo and secret query parameters of value if it
+// is a URL, and returns anything else unchanged
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return value
+	}
+	changed := false
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil && secretName(unescaped) {
+			params[i] = name + "=" + redacted
+			changed = true
+		}
+	}
+	u.RawQuery = strings.Join(params, "&")
+	if u.User != nil {
+		u.User = nil
+		return u.Scheme + "://" + redacted + "@" + strings.TrimPrefix(u.String(), u.Scheme+"://")
+	}
+	if !changed {
+		return value
+	}
+	return u.String()
+}
+
+// secretName reports whether a header or query parameter name suggests it
+// carries a credential, e.g. Authorization, X-API-Key or access_token
+func secretName(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, word := range []string{"auth", "token", "secret", "password"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, "key") || name == "sig" || name == "signature"
+}
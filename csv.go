@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader names the columns of a -csv file, in the order Write fills them
+var csvHeader = []string{"timestamp", "url", "connected", "status_code", "latency_ms", "error", "reason", "remote_ip", "response_bytes", "server"}
+
+// csvLog appends one row per check to a CSV file
+type csvLog struct {
+	f       *os.File
+	w       *csv.Writer
+	columns int    // of csvHeader the file has, fewer for one from an older version
+	movedTo string // where a file with other columns was moved aside, if one was
+}
+
+// openCSVLog opens path for appending, writing the header row if the file
+// is new or empty. A file whose columns are a prefix of csvHeader, such as
+// one from an older version, keeps them and gets rows cut down to match. A
+// file with any other columns is moved aside to a timestamped name and a
+// new one started, rather than getting rows that don't match its header.
+func openCSVLog(path string) (*csvLog, error) {
+	l, header, err := openCSVFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil && !isCSVHeaderPrefix(header) {
+		l.f.Close()
+		ext := filepath.Ext(path)
+		movedTo := strings.TrimSuffix(path, ext) + "-" + time.Now().Format("20060102-150405") + ext
+		if err := os.Rename(path, movedTo); err != nil {
+			return nil, fmt.Errorf("%s has the columns %s rather than %s and can't be moved aside: %w",
+				path, strings.Join(header, ","), strings.Join(csvHeader, ","), err)
+		}
+		if l, _, err = openCSVFile(path); err != nil {
+			return nil, err
+		}
+		l.movedTo = movedTo
+	}
+	return l, nil
+}
+
+// openCSVFile opens path for appending, returning the header of a file
+// that already has one or writing csvHeader to a new or empty file
+func openCSVFile(path string) (*csvLog, []string, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	l := &csvLog{f: f, w: csv.NewWriter(f), columns: len(csvHeader)}
+	if info.Size() > 0 {
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = -1
+		header, err := r.Read()
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("%s: reading the header: %w", path, err)
+		}
+		l.columns = len(header)
+		return l, header, nil
+	}
+	l.w.Write(csvHeader)
+	l.w.Flush()
+	if err := l.w.Error(); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return l, nil, nil
+}
+
+// isCSVHeaderPrefix reports whether header is the first columns of csvHeader
+func isCSVHeaderPrefix(header []string) bool {
+	return len(header) <= len(csvHeader) && slices.Equal(header, csvHeader[:len(header)])
+}
+
+// Write appends the result of a check against url, flushing it to the file
+// straight away so partial data survives a crash
+func (l *csvLog) Write(url string, r checkResult) error {
+	errText := ""
+	if r.Err != nil {
+		errText = r.Err.Error()
+	}
+	remoteIP := ""
+	if r.RemoteIP != nil {
+		remoteIP = r.RemoteIP.String()
+	}
+	size := ""
+	if r.StatusCode != 0 && r.ResponseSize >= 0 {
+		size = strconv.FormatInt(r.ResponseSize, 10)
+	}
+	l.w.Write([]string{
+		stamp(r.Time),
+		url,
+		strconv.FormatBool(r.Connected),
+		strconv.Itoa(r.StatusCode),
+		strconv.FormatFloat(durationMs(r.Latency), 'f', 3, 64),
+		errText,
+		r.Reason,
+		remoteIP,
+		size,
+		r.Server,
+	}[:l.columns])
+	l.w.Flush()
+	return l.w.Error()
+}
+
+func (l *csvLog) Close() error {
+	l.w.Flush()
+	return l.f.Close()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readCSV returns every row of the CSV file at path
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestCSVLogAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.csv")
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		l, err := openCSVLog(path)
+		if err != nil {
+			t.Fatalf("open %d: %v", i+1, err)
+		}
+		if err := l.Write("https://example.com", checkResult{Time: at, Connected: true, StatusCode: 200, Latency: 12 * time.Millisecond, ResponseSize: 512, Server: "nginx"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows := readCSV(t, path)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want the header and a row per run:\n%q", len(rows), rows)
+	}
+	if !slices.Equal(rows[0], csvHeader) {
+		t.Errorf("header = %q, want %q", rows[0], csvHeader)
+	}
+	for _, row := range rows[1:] {
+		if len(row) != len(csvHeader) || row[1] != "https://example.com" || row[8] != "512" || row[9] != "nginx" {
+			t.Errorf("row = %q", row)
+		}
+	}
+}
+
+func TestCSVLogKeepsOlderColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.csv")
+	old := "timestamp,url,connected,status_code,latency_ms,error\n2024-01-01T12:00:00Z,https://example.com,true,200,12.000,\n"
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := openCSVLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write("https://example.com", checkResult{Time: time.Now(), Connected: true, StatusCode: 200, Server: "nginx"}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	rows := readCSV(t, path)
+	if len(rows) != 3 || len(rows[2]) != 6 || rows[2][1] != "https://example.com" {
+		t.Errorf("rows = %q, want a row with the file's 6 columns appended", rows)
+	}
+}
+
+func TestCSVLogMovesOtherColumnsAside(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checks.csv")
+	old := "when,where\n2024-01-01T12:00:00Z,https://example.com\n"
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := openCSVLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	if filepath.Dir(l.movedTo) != dir || !strings.HasPrefix(filepath.Base(l.movedTo), "checks-") || filepath.Ext(l.movedTo) != ".csv" {
+		t.Fatalf("moved to %q, want a timestamped checks-*.csv beside it", l.movedTo)
+	}
+	data, err := os.ReadFile(l.movedTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != old {
+		t.Errorf("moved file was changed:\n%s", data)
+	}
+	if rows := readCSV(t, path); len(rows) != 1 || !slices.Equal(rows[0], csvHeader) {
+		t.Errorf("new file = %q, want just the header", rows)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Commands the dashboard sends back to the main loop from its keybindings.
+// Quitting needs none, the program ending closes dashboard.done.
+const (
+	dashboardPause = "pause"
+	dashboardReset = "reset"
+)
+
+const (
+	// dashboardEvents is how many recent events the dashboard lists
+	dashboardEvents = 10
+
+	// dashboardChartHeight is the number of rows in each latency chart
+	dashboardChartHeight = 4
+)
+
+// dashboard is the interactive -display dashboard, a bubbletea program fed
+// with snapshots of the targets by the main loop
+type dashboard struct {
+	program  *tea.Program
+	commands chan string
+	done     chan struct{}
+}
+
+// dashboardTarget is a copy of what the dashboard shows for one target, so
+// the program never reads a target while the main loop updates it
+type dashboardTarget struct {
+	url      string
+	state    connState
+	reason   string
+	latency  string
+	uptime   string
+	recent   []windowEntry
+	recentOK string
+}
+
+// dashboardUpdate carries fresh target snapshots into the program
+type dashboardUpdate []dashboardTarget
+
+// dashboardEvent adds a line to the event log
+type dashboardEvent string
+
+func newDashboard(d *display) *dashboard {
+	dash := &dashboard{commands: make(chan string, 8), done: make(chan struct{})}
+	dash.program = tea.NewProgram(&dashboardModel{display: d, commands: dash.commands}, tea.WithAltScreen())
+	return dash
+}
+
+// start runs the program in the background until stop or a quit key
+func (dash *dashboard) start() {
+	go func() {
+		defer close(dash.done)
+		dash.program.Run()
+	}()
+}
+
+// stop quits the program and waits for it to restore the terminal
+func (dash *dashboard) stop() {
+	dash.program.Quit()
+	<-dash.done
+}
+
+// update sends a snapshot of the targets to the program
+func (dash *dashboard) update(d *display, targets []*target) {
+	snapshot := make(dashboardUpdate, len(targets))
+	for i, t := range targets {
+		s := dashboardTarget{url: t.url, state: t.state(), reason: t.last.Reason, latency: "-", uptime: "-", recent: t.recent.Entries()}
+		if t.lastStatus {
+			s.latency = formatLatency(t.last.Latency, d.latencyUnit)
+		}
+		if uptime, c := d.uptime(t); c != nil {
+			s.uptime = c.Sprint(uptime)
+		}
+		if rate, ok := t.recent.SuccessRate(); ok {
+			s.recentOK = fmt.Sprintf("%.0f%% of last %d", rate, t.recent.Len())
+		}
+		snapshot[i] = s
+	}
+	dash.program.Send(snapshot)
+}
+
+// event adds msg to the event log, one line per line of msg
+func (dash *dashboard) event(msg string) {
+	for _, line := range strings.Split(strings.TrimSpace(msg), "\n") {
+		dash.program.Send(dashboardEvent(line))
+	}
+}
+
+// dashboardModel is the bubbletea model behind the dashboard
+type dashboardModel struct {
+	display  *display
+	commands chan<- string
+	targets  []dashboardTarget
+	events   []string
+	paused   bool
+	width    int
+}
+
+func (m *dashboardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case dashboardUpdate:
+		m.targets = msg
+	case dashboardEvent:
+		m.events = append(m.events, string(msg))
+		if len(m.events) > dashboardEvents {
+			m.events = m.events[len(m.events)-dashboardEvents:]
+		}
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "p":
+			if m.send(dashboardPause) {
+				m.paused = !m.paused
+			}
+		case "r":
+			m.send(dashboardReset)
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// send passes a command to the main loop, dropping it rather than freezing
+// the dashboard while the main loop is busy with a slow round of checks.
+// It reports whether the command was delivered.
+func (m *dashboardModel) send(cmd string) bool {
+	select {
+	case m.commands <- cmd:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *dashboardModel) View() string {
+	d := m.display
+	width := m.width
+	if width <= 0 {
+		width = defaultWidth
+	}
+
+	var b strings.Builder
+	b.WriteString("Internet Connection Monitor")
+	if m.paused {
+		b.WriteString("  " + d.warning.Sprint("[PAUSED]"))
+	}
+	b.WriteString("\n" + d.info.Sprint("p pause · r reset stats · q quit") + "\n\n")
+
+	for _, t := range m.targets {
+		var status string
+		switch t.state {
+		case stateUp:
+			status = d.success.Sprint(symbols.connectedStatus())
+		case stateDegraded:
+			status = d.warning.Sprint(symbols.warn + " DEGRADED")
+		default:
+			text := symbols.disconnectedStatus()
+			if t.reason != "" {
+				text += " (" + t.reason + ")"
+			}
+			status = d.failure.Sprint(text)
+		}
+		fmt.Fprintf(&b, "%s  %s  latency %s  uptime %s  %s\n", t.url, status, t.latency, t.uptime, t.recentOK)
+		for _, line := range latencyChart(t.recent, width-2, dashboardChartHeight) {
+			b.WriteString("  " + d.info.Sprint(line) + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("Events\n")
+	for _, e := range m.events {
+		b.WriteString("  " + e + "\n")
+	}
+	return b.String()
+}
+
+// latencyChart draws the latencies of the most recent entries, at most
+// width of them, as columns height rows tall scaled to the highest one.
+// Failed checks show as a dot on the bottom row.
+func latencyChart(entries []windowEntry, width, height int) []string {
+	if len(entries) > width {
+		entries = entries[len(entries)-width:]
+	}
+	var highest time.Duration
+	for _, e := range entries {
+		if e.ok {
+			highest = max(highest, e.latency)
+		}
+	}
+
+	levels := len(symbols.spark)
+	rows := make([]strings.Builder, height)
+	for _, e := range entries {
+		// Eighths of a row the column fills, at least one for any success
+		filled := 0
+		if e.ok && highest > 0 {
+			filled = max(int(int64(e.latency)*int64(height*levels)/int64(highest)), 1)
+		}
+		for r := range height {
+			// Rows are built top down, so row r covers the levels above
+			// (height-1-r) full rows
+			below := (height - 1 - r) * levels
+			switch {
+			case !e.ok && r == height-1:
+				rows[r].WriteRune(symbols.gap)
+			case filled >= below+levels:
+				rows[r].WriteRune(symbols.spark[levels-1])
+			case filled > below:
+				rows[r].WriteRune(symbols.spark[filled-below-1])
+			default:
+				rows[r].WriteRune(' ')
+			}
+		}
+	}
+	lines := make([]string, height)
+	for i := range rows {
+		lines[i] = rows[i].String()
+	}
+	return lines
+}
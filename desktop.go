@@ -0,0 +1,18 @@
+//go:build linux || darwin || windows || openbsd || netbsd || dragonfly
+
+package main
+
+import "github.com/gen2brain/beeep"
+
+// setupDesktop prepares desktop notifications
+func setupDesktop() error {
+	beeep.AppName = "networkcheck"
+	return nil
+}
+
+// desktopAlert pops up a desktop notification. The backend may be slow or
+// missing entirely (e.g. no notification daemon), which is not worth
+// interrupting monitoring over.
+func desktopAlert(title, message string) {
+	go beeep.Notify(title, message, "")
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || windows || openbsd || netbsd || dragonfly)
+
+package main
+
+import "errors"
+
+// setupDesktop fails, the desktop notification library doesn't build here
+func setupDesktop() error {
+	return errors.New("desktop notifications aren't supported on this platform")
+}
+
+func desktopAlert(title, message string) {}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Discord embed colors for each state
+const (
+	discordGreen  = 0x2ecc71
+	discordYellow = 0xf1c40f
+	discordRed    = 0xe74c3c
+)
+
+// discordNotifier posts state changes to a Discord webhook as an embed
+// colored by the new state
+type discordNotifier struct {
+	url    string
+	poster *poster
+
+	// cooldown suppresses messages that follow the last one too closely,
+	// so flapping doesn't spam the channel
+	cooldown time.Duration
+	lastSent time.Time
+}
+
+// discordPayload is the body of a Discord webhook message
+type discordPayload struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+	Timestamp   string `json:"timestamp"`
+}
+
+func (n *discordNotifier) Notify(c stateChange) {
+	if !n.lastSent.IsZero() && c.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = c.Time
+
+	embed := discordEmbed{
+		Title:       "Connection lost",
+		Description: fmt.Sprintf("%s is down, it was %s for %s", c.URL, c.From, formatDuration(c.Previous)),
+		Color:       discordRed,
+		Timestamp:   stamp(c.Time),
+	}
+	switch c.To {
+	case stateUp:
+		embed.Title = "Connection restored"
+		embed.Color = discordGreen
+		embed.Description = fmt.Sprintf("%s is up, it was %s for %s", c.URL, c.From, formatDuration(c.Previous))
+		if c.recovered() {
+			embed.Description = fmt.Sprintf("%s recovered after %s", c.URL, formatDuration(c.Previous))
+		}
+	case stateDegraded:
+		embed.Title = "Connection degraded"
+		embed.Color = discordYellow
+		embed.Description = fmt.Sprintf("%s is degraded, it was %s for %s", c.URL, c.From, formatDuration(c.Previous))
+	}
+	n.poster.post("discord", n.url, discordPayload{Embeds: []discordEmbed{embed}})
+}
+
+func (n *discordNotifier) NotifySpike(s latencySpike) {
+	if !n.lastSent.IsZero() && s.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = s.Time
+	n.poster.post("discord", n.url, discordPayload{Embeds: []discordEmbed{{
+		Title:       "Latency spike",
+		Description: s.String(),
+		Color:       discordYellow,
+		Timestamp:   stamp(s.Time),
+	}}})
+}
+
+func (n *discordNotifier) NotifySustained(s sustainedLatency) {
+	if !n.lastSent.IsZero() && s.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = s.Time
+	n.poster.post("discord", n.url, discordPayload{Embeds: []discordEmbed{{
+		Title:       "Sustained high latency",
+		Description: s.String(),
+		Color:       discordYellow,
+		Timestamp:   stamp(s.Time),
+	}}})
+}
+
+func (n *discordNotifier) NotifyFlap(f flapAlert) {
+	if !n.lastSent.IsZero() && f.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = f.Time
+	n.poster.post("discord", n.url, discordPayload{Embeds: []discordEmbed{{
+		Title:       "Connection flapping",
+		Description: f.String(),
+		Color:       discordYellow,
+		Timestamp:   stamp(f.Time),
+	}}})
+}
@@ -0,0 +1,730 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/term"
+)
+
+// display renders the live connection status to stdout
+type display struct {
+	// Success, failure and warning formatters
+	success *color.Color
+	failure *color.Color
+	warning *color.Color
+	info    *color.Color
+
+	// Latency colors, below latencyGood is fast and above latencyBad slow
+	fast        *color.Color
+	fair        *color.Color
+	slow        *color.Color
+	latencyGood time.Duration
+	latencyBad  time.Duration
+
+	// trendDeadband is the percentage either side of the average latency
+	// shown as steady rather than rising or falling
+	trendDeadband float64
+
+	// plain appends a line per check instead of redrawing in place with
+	// cursor movement
+	plain bool
+
+	// quiet prints state changes only, skipping the banner and per-check
+	// output
+	quiet bool
+
+	// compact rewrites a single line with every target's status in place of
+	// the banner and table
+	compact bool
+
+	// trace adds each target's latency breakdown below its status
+	trace bool
+
+	// latencyUnit is the -latency-unit latencies are shown in
+	latencyUnit string
+
+	// certWarnDays is how close to expiry a TLS certificate gets a warning
+	certWarnDays int
+
+	// nextCheck is when checks resume while backed off, zero otherwise
+	nextCheck time.Time
+
+	// width is the terminal width lines are cut to, refreshed by resize.
+	// widthKnown is false when it is only defaultWidth.
+	width      int
+	widthKnown bool
+
+	// banner holds the lines start printed above the table, for redraws
+	banner []string
+
+	// top is the first screen row below the banner
+	top int
+
+	// bottom is the first screen row below everything table drew
+	bottom int
+
+	// quorum is the -quorum target count, 0 when not checking a quorum
+	quorum int
+
+	// gateway is the -gateway check shown above the targets, nil if none
+	gateway *target
+
+	// keys receives keypresses in the table, nil when stdin isn't a
+	// terminal, and restoreKeys undoes the terminal mode change
+	keys        <-chan byte
+	restoreKeys func()
+
+	// paused is shown in the status line while checks are paused
+	paused bool
+
+	// dash, when set, takes over from the built-in rendering
+	dash *dashboard
+
+	// stopped is set once stop has restored the terminal
+	stopped bool
+
+	// notice is the latest out-of-band message. announce writes it to
+	// stderr once, and every redraw repaints it below the table on stdout so
+	// it isn't wiped out.
+	notice string
+}
+
+func newDisplay(plain bool) *display {
+	d := &display{
+		success: color.New(color.FgGreen, color.Bold),
+		failure: color.New(color.FgRed, color.Bold),
+		warning: color.New(color.FgYellow, color.Bold),
+		info:    color.New(color.FgCyan),
+		fast:    color.New(color.FgGreen),
+		fair:    color.New(color.FgYellow),
+		slow:    color.New(color.FgRed),
+		plain:   plain,
+	}
+	d.width, d.widthKnown = terminalWidth()
+	return d
+}
+
+// start prints the banner with any extra details lines, clearing the screen
+// first when redrawing in place
+func (d *display) start(urls string, details ...string) {
+	if d.dash != nil {
+		d.dash.start()
+		return
+	}
+	if d.quiet {
+		return
+	}
+	if d.compact {
+		fmt.Print("\033[?25l")
+		return
+	}
+	// Keypresses are only read in the table, where checks can be paused
+	// with the spacebar
+	help := "Press Ctrl+C to exit"
+	if !d.plain {
+		if keys, restore, err := readKeys(); err == nil {
+			d.keys, d.restoreKeys = keys, restore
+			help = "Press Space to pause, Ctrl+C to exit"
+		}
+	}
+	d.banner = append([]string{"Internet Connection Monitor", "Testing connection to: " + urls}, details...)
+	d.banner = append(d.banner, help, "----------------------------")
+	d.top = len(d.banner) + 1
+	if d.plain {
+		for _, line := range d.banner {
+			fmt.Println(line)
+		}
+		return
+	}
+	// Hide cursor, then draw the banner on a cleared screen
+	fmt.Print("\033[?25l")
+	d.drawBanner()
+}
+
+// drawBanner clears the screen and prints the banner, cut to the terminal
+// width so a long target list can't wrap and push the table down
+func (d *display) drawBanner() {
+	fmt.Print("\033[H\033[2J")
+	for _, line := range d.banner {
+		w := &lineWriter{left: d.width}
+		w.print(nil, line)
+		fmt.Println()
+	}
+}
+
+// resize picks up a new terminal width, redrawing everything when the
+// display is drawn in place
+func (d *display) resize(targets []*target) {
+	d.width, d.widthKnown = terminalWidth()
+	switch {
+	case d.dash != nil || d.plain || d.quiet:
+	case d.compact:
+		d.compactLine(targets)
+	default:
+		d.drawBanner()
+		d.table(targets)
+	}
+}
+
+// stop restores the terminal state changed by start, and may be called
+// more than once
+func (d *display) stop() {
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	if d.dash != nil {
+		d.dash.stop()
+		return
+	}
+	if d.restoreKeys != nil {
+		d.restoreKeys()
+	}
+	if !d.plain {
+		fmt.Print("\033[?25h") // Show cursor when done
+	}
+}
+
+// update shows the latest results for all targets, of which the log
+// display only prints those in checked
+func (d *display) update(targets, checked []*target) {
+	switch {
+	case d.dash != nil:
+		d.dash.update(d, targets)
+	case d.quiet:
+	case d.compact:
+		d.compactLine(targets)
+	case d.plain:
+		for _, t := range checked {
+			d.line(t)
+		}
+		if d.gateway != nil {
+			fmt.Printf("[%s] gateway %s %s\n", clock(d.gateway.last.Time), d.gateway.url, d.gatewayStatus())
+		}
+		if d.quorum > 0 {
+			fmt.Printf("[%s] quorum %s\n", clock(time.Now()), d.quorumStatus(targets))
+		}
+	default:
+		d.table(targets)
+	}
+}
+
+// change prints a line for a target changing state in quiet mode, or logs
+// it as a dashboard event
+func (d *display) change(c stateChange) {
+	if d.dash != nil {
+		d.dash.event(fmt.Sprintf("[%s] %s is %s (was %s for %s)", clock(c.Time), c.URL, c.To, c.From, formatDuration(c.Previous)))
+		return
+	}
+	if !d.quiet {
+		return
+	}
+	fmt.Printf("[%s] %s ", clock(c.Time), c.URL)
+	switch c.To {
+	case stateUp:
+		d.success.Print(symbols.connectedStatus())
+	case stateDegraded:
+		d.warning.Print(symbols.warn + " DEGRADED")
+	default:
+		d.failure.Print(symbols.disconnectedStatus())
+	}
+	fmt.Printf(" (was %s for %s)\n", c.From, formatDuration(c.Previous))
+}
+
+// compactLine rewrites the current line with a short status of every
+// target, clearing whatever is left of a longer previous one
+func (d *display) compactLine(targets []*target) {
+	fmt.Print("\r")
+	// One column is left free so the cursor never wraps onto the next line
+	w := &lineWriter{left: d.width - 1}
+	if d.quorum > 0 {
+		w.print(nil, "quorum "+d.quorumStatus(targets)+" | ")
+	}
+	for i, t := range targets {
+		if i > 0 {
+			w.print(nil, " | ")
+		}
+		if len(targets) > 1 {
+			w.print(nil, t.url+" ")
+		}
+		switch t.state() {
+		case stateUp:
+			w.print(d.success, symbols.ok)
+		case stateDegraded:
+			w.print(d.warning, symbols.warn)
+		default:
+			w.print(d.failure, symbols.fail)
+		}
+		if t.lastStatus {
+			w.print(nil, " ")
+			w.print(d.latencyColor(t.last.Latency), formatLatency(t.last.Latency, d.latencyUnit))
+			arrow, c := d.trend(t)
+			w.print(c, fmt.Sprintf(" %c", arrow))
+		}
+		if uptime, c := d.uptime(t); c != nil {
+			w.print(c, " "+uptime)
+		}
+		if t.flaps.Flapping(t.lastCheckTime) {
+			w.print(d.warning, " FLAPPING")
+		}
+		if t.warmingUp() {
+			w.print(d.info, " warming up")
+		}
+	}
+	fmt.Print("\033[K")
+}
+
+// trend compares a target's latest latency with the average of its recent
+// successful checks, ignoring differences within the -trend-deadband
+// percentage, and returns the arrow with its color: red when latency is
+// rising, green when it is falling
+func (d *display) trend(t *target) (rune, *color.Color) {
+	var total time.Duration
+	n := 0
+	for _, e := range t.recent.Entries() {
+		if e.ok {
+			total += e.latency
+			n++
+		}
+	}
+	if n == 0 || !t.last.Connected {
+		return symbols.steady, nil
+	}
+	avg := total / time.Duration(n)
+	band := time.Duration(float64(avg) * d.trendDeadband / 100)
+	switch {
+	case t.last.Latency > avg+band:
+		return symbols.rising, d.slow
+	case t.last.Latency < avg-band:
+		return symbols.falling, d.fast
+	}
+	return symbols.steady, nil
+}
+
+// line appends a single timestamped line with a target's latest result
+func (d *display) line(t *target) {
+	fmt.Printf("[%s] %s ", clock(t.last.Time), t.url)
+	if t.lastStatus {
+		if t.degraded {
+			d.warning.Print(symbols.warn + " DEGRADED")
+		} else {
+			d.success.Print(symbols.connectedStatus())
+		}
+		d.latencyColor(t.last.Latency).Printf(" %s", formatLatency(t.last.Latency, d.latencyUnit))
+		if t.latencyCount > 0 {
+			fmt.Printf(" (smoothed %s)", formatLatency(t.latencyEWMA.Value(), d.latencyUnit))
+		}
+		if t.last.FallbackURL != "" {
+			d.warning.Printf(" via fallback %s", t.last.FallbackURL)
+		}
+		if t.last.Mbps > 0 {
+			fmt.Printf(" %.1f Mbps", t.last.Mbps)
+		}
+		if b := t.last.Burst; b != nil {
+			c := d.info
+			if b.Lost > 0 {
+				c = d.warning
+			}
+			c.Printf(" loss %.0f%%", b.loss())
+			fmt.Printf(" (min %s, max %s)", formatLatency(b.Min, d.latencyUnit), formatLatency(b.Max, d.latencyUnit))
+		}
+		if d.trace && t.last.Phases != nil {
+			fmt.Printf(" (%s)", d.phases(t.last.Phases))
+		}
+		if d.trace && t.last.RemoteIP != nil {
+			fmt.Printf(" via %s", remote(t.last.RemoteIP))
+		}
+		if d.trace && t.last.FinalURL != "" {
+			fmt.Printf(" redirected to %s", t.last.FinalURL)
+		}
+		if warning, ok := d.certWarning(t); ok {
+			d.warning.Printf(" %s", warning)
+		}
+		if uptime, c := d.uptime(t); c != nil {
+			c.Printf(" uptime %s", uptime)
+		}
+		if t.flaps.Flapping(t.lastCheckTime) {
+			d.warning.Print(" FLAPPING")
+		}
+		if t.warmingUp() {
+			d.info.Print(" warming up")
+		}
+		fmt.Println()
+		if outage, ok := t.lastOutage(); ok && outage.End.Equal(t.lastCheckTime) {
+			d.success.Printf("[%s] %s recovered after %s\n", clock(t.last.Time), t.url, formatDuration(outage.duration(outage.End)))
+		}
+		return
+	}
+	d.failure.Print(symbols.disconnectedStatus())
+	if t.last.Reason != "" {
+		fmt.Printf(" (%s)", t.last.Reason)
+	}
+	if recovering, ok := t.recovering(); ok {
+		d.warning.Printf(" %s", recovering)
+	}
+	if diagnosis := t.last.diagnosis(); diagnosis != "" {
+		d.warning.Printf(" %s", diagnosis)
+	}
+	if uptime, c := d.uptime(t); c != nil {
+		c.Printf(" uptime %s", uptime)
+	}
+	if t.flaps.Flapping(t.lastCheckTime) {
+		d.warning.Print(" FLAPPING")
+	}
+	if t.warmingUp() {
+		d.info.Print(" warming up")
+	}
+	fmt.Println()
+}
+
+// quorumStatus describes the -quorum verdict, e.g. "✓ UP (2/3, need 2) 12ms"
+func (d *display) quorumStatus(targets []*target) string {
+	q := quorumVerdict(targets, d.quorum)
+	counts := fmt.Sprintf(" (%d/%d, need %d)", q.ok, q.total, d.quorum)
+	if !q.up {
+		return d.failure.Sprint(symbols.fail+" DOWN") + counts
+	}
+	return d.success.Sprint(symbols.ok+" UP") + counts + " " + formatLatency(q.latency, d.latencyUnit)
+}
+
+// gatewayStatus describes whether the gateway answered its last check
+func (d *display) gatewayStatus() string {
+	if d.gateway.lastStatus {
+		return d.success.Sprint(symbols.ok+" reachable") + " " + formatLatency(d.gateway.last.Latency, d.latencyUnit)
+	}
+	return d.failure.Sprint(symbols.fail + " unreachable")
+}
+
+// latencyColor picks the color a latency is shown in, purely cosmetic
+func (d *display) latencyColor(l time.Duration) *color.Color {
+	switch {
+	case l < d.latencyGood:
+		return d.fast
+	case l > d.latencyBad:
+		return d.slow
+	}
+	return d.fair
+}
+
+// Session uptime percentages at or above uptimeGood show in green, those
+// below uptimeFair in red and the rest in yellow
+const (
+	uptimeGood = 99.0
+	uptimeFair = 95.0
+)
+
+// uptime formats a target's session uptime percentage with its color, or
+// "-" before anything has been tracked
+func (d *display) uptime(t *target) (string, *color.Color) {
+	pct, ok := t.uptimePercent()
+	switch {
+	case !ok:
+		return "-", nil
+	case pct >= uptimeGood:
+		return fmt.Sprintf("%.1f%%", pct), d.fast
+	case pct < uptimeFair:
+		return fmt.Sprintf("%.1f%%", pct), d.slow
+	}
+	return fmt.Sprintf("%.1f%%", pct), d.fair
+}
+
+// phases formats a latency breakdown on one line
+func (d *display) phases(p *phaseTimings) string {
+	var s string
+	if p.Reused {
+		s = fmt.Sprintf("reused connection, ttfb %s", formatLatency(p.TTFB, d.latencyUnit))
+	} else {
+		s = fmt.Sprintf("dns %s, connect %s, tls %s, ttfb %s",
+			formatLatency(p.DNS, d.latencyUnit), formatLatency(p.Connect, d.latencyUnit),
+			formatLatency(p.TLS, d.latencyUnit), formatLatency(p.TTFB, d.latencyUnit))
+	}
+	return s
+}
+
+// remote describes the server address a check connected to along with its
+// IP family, e.g. "93.184.216.34 (IPv4)"
+func remote(ip net.IP) string {
+	if ip.To4() != nil {
+		return ip.String() + " (IPv4)"
+	}
+	return ip.String() + " (IPv6)"
+}
+
+// table redraws the per-target table with each target's status, latency and uptime.
+func (d *display) table(targets []*target) {
+	// Status line is the first row below the banner
+	w := d.row(d.top)
+	w.print(d.info, fmt.Sprintf("[%s] Last check", clock(time.Now())))
+	if d.paused {
+		w.print(d.warning, "  PAUSED")
+	}
+	if d.quorum > 0 {
+		w.print(nil, "  Quorum: "+d.quorumStatus(targets))
+	}
+	if !d.nextCheck.IsZero() {
+		w.print(d.warning, fmt.Sprintf("  backed off, next check at %s", clock(d.nextCheck)))
+	}
+
+	// Gateway on the row between the status line and the table
+	if d.gateway != nil {
+		w = d.row(d.top + 1)
+		w.print(nil, "Gateway "+d.gateway.url+": ")
+		if d.gateway.lastStatus {
+			w.print(d.success, symbols.ok+" reachable")
+			w.print(nil, " "+formatLatency(d.gateway.last.Latency, d.latencyUnit))
+		} else {
+			w.print(d.failure, symbols.fail+" unreachable")
+		}
+	}
+
+	// Table header two rows below, then a row per target followed by its
+	// latency breakdown when tracing, the server address it reached and its
+	// latency sparkline. The target column gives up space first on narrow
+	// terminals, then whatever no longer fits on the right is cut off.
+	targetWidth := min(max(d.width-89, 20), 40)
+	w = d.row(d.top + 2)
+	w.print(nil, cell("TARGET", targetWidth)+cell("STATUS", 30)+cell("LATENCY", 10)+cell("SMOOTHED", 10)+cell("JITTER", 10)+cell("UPTIME", 8)+"RECENT")
+	row := d.top + 3
+	for _, t := range targets {
+		w = d.row(row)
+		row++
+		w.print(nil, cell(t.url, targetWidth))
+
+		// Print connection status with color
+		if t.lastStatus {
+			if t.degraded {
+				w.print(d.warning, cell(symbols.warn+" DEGRADED", 30))
+			} else {
+				w.print(d.success, cell(symbols.connectedStatus(), 30))
+			}
+			arrow, c := d.trend(t)
+			w.print(d.latencyColor(t.last.Latency), cell(formatLatency(t.last.Latency, d.latencyUnit), 8))
+			w.print(c, cell(string(arrow), 1))
+		} else {
+			status := symbols.disconnectedStatus()
+			if t.last.Reason != "" {
+				status += " (" + t.last.Reason + ")"
+			}
+			if recovering, ok := t.recovering(); ok {
+				status += " " + recovering
+			}
+			w.print(d.failure, cell(status, 30))
+			w.print(nil, cell("-", 10))
+		}
+		if t.latencyCount > 0 {
+			w.print(nil, cell(formatLatency(t.latencyEWMA.Value(), d.latencyUnit), 10))
+			w.print(nil, cell(formatLatency(t.jitter(), d.latencyUnit), 10))
+		} else {
+			w.print(nil, cell("-", 10)+cell("-", 10))
+		}
+
+		uptime, c := d.uptime(t)
+		w.print(c, cell(uptime, 8))
+
+		// Success rate over the rolling window of recent checks
+		if rate, ok := t.recent.SuccessRate(); ok {
+			w.print(nil, fmt.Sprintf("%.0f%% of last %d", rate, t.recent.Len()))
+		}
+
+		if d.trace {
+			w = d.row(row)
+			row++
+			if t.last.Phases != nil && t.last.Connected {
+				w.print(d.info, "  "+d.phases(t.last.Phases))
+			}
+			if t.last.FinalURL != "" {
+				w.print(d.info, ", redirected to "+t.last.FinalURL)
+			}
+		}
+
+		w = d.row(row)
+		row++
+		if t.last.RemoteIP != nil {
+			w.print(d.info, "  Remote: "+remote(t.last.RemoteIP))
+		}
+		if t.last.FallbackURL != "" {
+			w.print(d.warning, "  Fallback: "+t.last.FallbackURL)
+		}
+		if t.warmingUp() {
+			w.print(d.info, "  Warming up, not counted in the statistics")
+		}
+		if t.flaps.Flapping(t.lastCheckTime) {
+			w.print(d.warning, fmt.Sprintf("  %s FLAPPING: %d state changes in %s", symbols.warn, t.flaps.Count(), formatDuration(t.flaps.window)))
+		}
+		if s := t.throughput; s.count > 0 {
+			w.print(d.info, fmt.Sprintf("  Throughput: %.1f Mbps (min %.1f, max %.1f, avg %.1f)", t.last.Mbps, s.min, s.max, s.avg()))
+		}
+
+		w = d.row(row)
+		row++
+		sparkWidth := defaultSparklineWidth
+		if d.widthKnown {
+			sparkWidth = max(d.width-2, 1)
+		}
+		w.print(d.info, "  "+sparkline(t.recent.Entries(), sparkWidth))
+	}
+
+	// Warnings go below the table, clearing whatever was left there by the
+	// previous redraw
+	fmt.Printf("\033[%d;0H\033[J", row+1)
+	d.bottom = row + 1
+	for _, t := range targets {
+		if diagnosis := t.last.diagnosis(); diagnosis != "" && !t.lastStatus {
+			w = &lineWriter{left: d.width}
+			w.print(d.warning, fmt.Sprintf("%s %s: %s", symbols.fail, t.url, diagnosis))
+			fmt.Println()
+			d.bottom++
+		}
+		if outage, ok := t.lastOutage(); ok {
+			longest, _ := t.outageStats()
+			w = &lineWriter{left: d.width}
+			w.print(d.success, fmt.Sprintf("%s %s: recovered after %s at %s", symbols.ok, t.url, formatDuration(outage.duration(outage.End)), clock(outage.End)))
+			w.print(nil, fmt.Sprintf(", longest outage %s", formatDuration(longest)))
+			fmt.Println()
+			d.bottom++
+		}
+		if warning, ok := d.certWarning(t); ok {
+			w = &lineWriter{left: d.width}
+			w.print(d.warning, fmt.Sprintf("%s %s: %s", symbols.warn, t.url, warning))
+			fmt.Println()
+			d.bottom++
+		}
+	}
+	fmt.Print(d.notice)
+}
+
+// row moves the cursor to the start of a screen row and clears it,
+// returning a writer for the row's contents
+func (d *display) row(n int) *lineWriter {
+	fmt.Printf("\033[%d;0H\033[K", n)
+	return &lineWriter{left: d.width}
+}
+
+// lineWriter prints the pieces of a screen line, dropping whatever goes
+// past the terminal width instead of letting it wrap
+type lineWriter struct {
+	left int // columns still free
+}
+
+// print writes s in color c, or uncolored when c is nil, cutting it short
+// once the line is full
+func (w *lineWriter) print(c *color.Color, s string) {
+	r := []rune(s)
+	if len(r) > w.left {
+		r = r[:max(w.left, 0)]
+	}
+	w.left -= len(r)
+	if len(r) == 0 {
+		return
+	}
+	if c != nil {
+		c.Print(string(r))
+	} else {
+		fmt.Print(string(r))
+	}
+}
+
+// cell pads s to a column of the given width followed by a space,
+// shortening it with an ellipsis when it doesn't fit
+func cell(s string, width int) string {
+	if r := []rune(s); len(r) > width {
+		s = string(r[:width-1]) + symbols.ellipsis
+	}
+	return fmt.Sprintf("%-*s ", width, s)
+}
+
+// summary prints a rollup in the output stream, below the table when
+// redrawing in place
+func (d *display) summary(msg string) {
+	switch {
+	case d.dash != nil:
+		d.dash.event(msg)
+	case d.compact:
+		fmt.Print("\r\033[K" + msg)
+	case d.plain:
+		fmt.Print(msg)
+	default:
+		d.announce(msg)
+	}
+}
+
+// announce writes an out-of-band message to stderr, below the table when
+// redrawing in place
+func (d *display) announce(msg string) {
+	if d.dash != nil {
+		d.dash.event(msg)
+		return
+	}
+	if d.compact {
+		fmt.Fprint(os.Stderr, "\r\033[K"+strings.TrimLeft(msg, "\n"))
+		return
+	}
+	if d.plain {
+		fmt.Fprint(os.Stderr, msg)
+		return
+	}
+	d.notice = msg
+	fmt.Printf("\033[%d;0H\033[J", d.bottom)
+	fmt.Fprint(os.Stderr, d.notice)
+}
+
+// certWarning describes a target's TLS certificate expiring within
+// certWarnDays, and reports false when there's nothing to warn about
+func (d *display) certWarning(t *target) (string, bool) {
+	if t.last.CertExpiry.IsZero() {
+		return "", false
+	}
+	days := int(time.Until(t.last.CertExpiry).Hours() / 24)
+	if days >= d.certWarnDays {
+		return "", false
+	}
+	if days < 0 {
+		return fmt.Sprintf("TLS certificate expired on %s", t.last.CertExpiry.Format(time.DateOnly)), true
+	}
+	return fmt.Sprintf("TLS certificate expires in %d days (%s)", days, t.last.CertExpiry.Format(time.DateOnly)), true
+}
+
+// defaultWidth is assumed when the terminal width can't be determined
+const defaultWidth = 80
+
+// defaultSparklineWidth is how many checks the sparkline shows when the
+// terminal width can't be determined
+const defaultSparklineWidth = 40
+
+// terminalWidth returns the width of the terminal on stdout, or defaultWidth
+// and false when it can't be determined
+func terminalWidth() (int, bool) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return defaultWidth, false
+	}
+	return width, true
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// formatDuration returns a human-readable string for a time.Duration (e.g., 1h 2m 3s)
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+
+	if h > 0 {
+		return fmt.Sprintf("%dh %dm %ds", h, m, s)
+	} else if m > 0 {
+		return fmt.Sprintf("%dm %ds", m, s)
+	}
+	return fmt.Sprintf("%ds", s)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// dnsProber checks that a hostname resolves, measuring how long the lookup
+// takes, independently of whether the host is reachable
+type dnsProber struct {
+	host      string
+	timeout   time.Duration
+	ipVersion string
+	resolver  *net.Resolver
+}
+
+// newResolver returns the resolver for -mode dns: the system's, or one that
+// sends every query to server (host:port) from src when either is set
+func newResolver(server string, timeout time.Duration, src source) *net.Resolver {
+	if server == "" && src.ip == nil && src.device == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if server != "" {
+				address = server
+			}
+			return src.dialer(timeout).DialContext(ctx, network, address)
+		},
+	}
+}
+
+func (p *dnsProber) Probe(ctx context.Context) checkResult {
+	start := time.Now()
+	result := checkResult{Time: start}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	_, err := p.resolver.LookupIP(ctx, familyNetwork("ip", p.ipVersion), p.host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if p.ipVersion != "" && errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			err = &noAddressError{host: p.host, ipVersion: p.ipVersion}
+		}
+		result.Err = err
+		result.Reason = dnsFailureReason(err)
+		return result
+	}
+	result.Latency = time.Since(start)
+	result.Connected = true
+	return result
+}
+
+// dnsFailureReason tells a name that doesn't exist apart from a resolver
+// that can't be reached, which failureReason both reports as DNS
+func dnsFailureReason(err error) string {
+	var dnsErr *net.DNSError
+	switch {
+	case !errors.As(err, &dnsErr):
+		return failureReason(err)
+	case dnsErr.IsNotFound:
+		return "NXDOMAIN"
+	case dnsErr.IsTimeout:
+		return "RESOLVER UNREACHABLE"
+	}
+	// The resolver keeps only the text of network errors
+	for _, errno := range []syscall.Errno{syscall.ECONNREFUSED, syscall.ENETUNREACH, syscall.EHOSTUNREACH} {
+		if strings.HasSuffix(dnsErr.Err, errno.Error()) {
+			return "RESOLVER UNREACHABLE"
+		}
+	}
+	return "DNS"
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// eventWriteTimeout bounds how long a slow client can hold up its writer
+const eventWriteTimeout = time.Second
+
+// eventClientBuffer is how many events can queue for a client before it is
+// dropped as too slow to keep up
+const eventClientBuffer = 64
+
+// eventSocket streams the events -notify-on passes on, as one JSON object
+// per line in the -webhook format, to each client connected to a Unix socket
+type eventSocket struct {
+	ln      *net.UnixListener
+	writers sync.WaitGroup
+
+	mu      sync.Mutex
+	clients map[*eventClient]struct{}
+	closed  bool
+}
+
+// eventClient is a connected client and the events queued for it, written
+// by its own goroutine so a slow client never holds up the main loop
+type eventClient struct {
+	conn   net.Conn
+	events chan []byte
+}
+
+// listenEventSocket listens on the Unix socket at path, first removing a
+// stale socket left by a monitor that didn't shut down cleanly
+func listenEventSocket(path string) (*eventSocket, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	s := &eventSocket{ln: ln, clients: make(map[*eventClient]struct{})}
+	go s.accept()
+	return s, nil
+}
+
+// accept registers clients until the listener is closed
+func (s *eventSocket) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Error("event socket accept failed", "error", err)
+			continue
+		}
+		c := &eventClient{conn: conn, events: make(chan []byte, eventClientBuffer)}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.clients[c] = struct{}{}
+		s.writers.Add(1)
+		s.mu.Unlock()
+		go s.write(c)
+	}
+}
+
+// write sends the events queued for c until its queue is closed, then
+// disconnects it. A failed write drops the client.
+func (s *eventSocket) write(c *eventClient) {
+	defer s.writers.Done()
+	defer c.conn.Close()
+	for line := range c.events {
+		c.conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if _, err := c.conn.Write(line); err != nil {
+			s.mu.Lock()
+			if s.drop(c) {
+				slog.Info("event socket client dropped", "error", err)
+			}
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// drop forgets c and closes its queue, which ends its writer, reporting
+// whether c was still connected. s.mu must be held.
+func (s *eventSocket) drop(c *eventClient) bool {
+	if _, ok := s.clients[c]; !ok {
+		return false
+	}
+	delete(s.clients, c)
+	close(c.events)
+	return true
+}
+
+func (s *eventSocket) Notify(c stateChange) {
+	s.send(newWebhookPayload(c))
+}
+
+func (s *eventSocket) NotifySpike(spike latencySpike) {
+	s.send(newSpikePayload(spike))
+}
+
+func (s *eventSocket) NotifySustained(sustained sustainedLatency) {
+	s.send(newSustainedPayload(sustained))
+}
+
+func (s *eventSocket) NotifyFlap(f flapAlert) {
+	s.send(newFlapPayload(f))
+}
+
+// send queues event for every client, dropping those whose queue is full
+func (s *eventSocket) send(event webhookPayload) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("cannot encode event", "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c.events <- line:
+		default:
+			slog.Info("event socket client dropped", "error", "too slow to keep up")
+			s.drop(c)
+			c.conn.Close()
+		}
+	}
+}
+
+// Close stops listening, which removes the socket file, and disconnects
+// every client once the events already queued for it are written
+func (s *eventSocket) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.clients {
+		s.drop(c)
+	}
+	s.mu.Unlock()
+	s.writers.Wait()
+	return err
+}
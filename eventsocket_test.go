@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// connectEventClient connects to s and waits until it is registered
+func connectEventClient(t *testing.T, s *eventSocket, path string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for deadline := time.Now().Add(time.Second); ; {
+		s.mu.Lock()
+		n := len(s.clients)
+		s.mu.Unlock()
+		if n > 0 {
+			return conn
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client never registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEventSocketStreams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	s, err := listenEventSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	conn := connectEventClient(t, s, path)
+
+	s.Notify(stateChange{URL: "https://example.com", From: stateUp, To: stateDown, Time: time.Now()})
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, `"status":"down"`) {
+		t.Errorf("event = %q, want a down status", line)
+	}
+}
+
+func TestEventSocketDropsSlowClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	s, err := listenEventSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	connectEventClient(t, s, path)
+
+	// The client never reads, so once the socket buffers fill its queue does
+	// too, and sending must still return promptly
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		change := stateChange{URL: "https://example.com/" + strings.Repeat("x", 4096), From: stateUp, To: stateDown, Time: time.Now()}
+		for range 10000 {
+			s.Notify(change)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sending blocked on a slow client")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) != 0 {
+		t.Error("slow client wasn't dropped")
+	}
+}
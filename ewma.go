@@ -0,0 +1,33 @@
+package main
+
+import "time"
+
+// defaultEWMAAlpha weighs each new latency at 30% against the history
+const defaultEWMAAlpha = 0.3
+
+// ewma is an exponentially weighted moving average of latency, smoothing
+// out the noise of individual checks. Higher alpha follows changes faster.
+type ewma struct {
+	alpha  float64
+	value  float64
+	seeded bool
+}
+
+// Add feeds a latency into the average, the first one seeds it
+func (e *ewma) Add(d time.Duration) {
+	if !e.seeded {
+		e.value, e.seeded = float64(d), true
+		return
+	}
+	e.value = e.alpha*float64(d) + (1-e.alpha)*e.value
+}
+
+// Value returns the current average, zero until the first Add
+func (e *ewma) Value() time.Duration {
+	return time.Duration(e.value)
+}
+
+// Reset forgets the history, keeping alpha
+func (e *ewma) Reset() {
+	e.value, e.seeded = 0, false
+}
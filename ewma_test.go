@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEWMA(t *testing.T) {
+	e := ewma{alpha: 0.5}
+	if e.Value() != 0 {
+		t.Fatalf("Value() before any Add = %s, want 0", e.Value())
+	}
+	ms := time.Millisecond
+	steps := []struct {
+		add, want time.Duration
+	}{
+		{100 * ms, 100 * ms}, // the first latency seeds the average
+		{200 * ms, 150 * ms},
+		{50 * ms, 100 * ms},
+		{50 * ms, 75 * ms},
+		{0, 37500 * time.Microsecond},
+	}
+	for i, s := range steps {
+		e.Add(s.add)
+		if got := e.Value(); got != s.want {
+			t.Errorf("after Add #%d (%s): Value() = %s, want %s", i+1, s.add, got, s.want)
+		}
+	}
+}
+
+func TestEWMAAlpha(t *testing.T) {
+	slow, fast := ewma{alpha: 0.1}, ewma{alpha: 0.9}
+	for _, e := range []*ewma{&slow, &fast} {
+		e.Add(10 * time.Millisecond)
+		e.Add(110 * time.Millisecond)
+	}
+	if slow.Value() != 20*time.Millisecond {
+		t.Errorf("alpha 0.1: Value() = %s, want 20ms", slow.Value())
+	}
+	if fast.Value() != 100*time.Millisecond {
+		t.Errorf("alpha 0.9: Value() = %s, want 100ms", fast.Value())
+	}
+}
+
+func TestEWMAReset(t *testing.T) {
+	e := ewma{alpha: 0.3}
+	e.Add(100 * time.Millisecond)
+	e.Add(200 * time.Millisecond)
+	e.Reset()
+	if e.Value() != 0 || e.alpha != 0.3 {
+		t.Fatalf("after Reset: Value() = %s, alpha %v, want 0 and 0.3", e.Value(), e.alpha)
+	}
+	// The next latency seeds it again instead of blending with zero
+	e.Add(40 * time.Millisecond)
+	if e.Value() != 40*time.Millisecond {
+		t.Errorf("Value() after reseeding = %s, want 40ms", e.Value())
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// failureReason sorts a check error into a short category for the display
+// and logs, e.g. DNS or REFUSED, or returns "" when it fits none of them
+func failureReason(err error) string {
+	var (
+		dnsErr      *net.DNSError
+		netErr      net.Error
+		certErr     *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+		authErr     x509.UnknownAuthorityError
+		hostErr     x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		noAddrErr   *noAddressError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &noAddrErr):
+		return "NO IPV" + noAddrErr.ipVersion
+	case errors.As(err, &dnsErr):
+		return "DNS"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "TIMEOUT"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "REFUSED"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "RESET"
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return "UNREACHABLE"
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &authErr), errors.As(err, &hostErr), errors.As(err, &invalidCert):
+		return "TLS"
+	case strings.Contains(err.Error(), "remote error: tls: "):
+		// An alert from the server, e.g. for a missing client certificate,
+		// whose type crypto/tls doesn't export
+		return "TLS"
+	}
+	return ""
+}
+
+// failureCategory groups a failed check for the -retry-summary tally,
+// folding every HTTP status into one category
+func failureCategory(r checkResult) string {
+	switch {
+	case r.StatusCode != 0 && strings.HasPrefix(r.Reason, "HTTP "):
+		return "HTTP status"
+	case r.Reason == "":
+		return "other"
+	}
+	return r.Reason
+}
+
+// failureTally counts failed checks by category
+type failureTally map[string]int
+
+// String lists the categories from most to least frequent, e.g.
+// "12 TIMEOUT, 3 DNS"
+func (f failureTally) String() string {
+	categories := slices.Collect(maps.Keys(f))
+	slices.SortFunc(categories, func(a, b string) int {
+		if c := cmp.Compare(f[b], f[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	parts := make([]string, len(categories))
+	for i, c := range categories {
+		parts[i] = fmt.Sprintf("%d %s", f[c], c)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// timeoutError is a net.Error that timed out, like a dial or read deadline
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// dialError wraps err the way a failed http.Client request does
+func dialError(err error) error {
+	return &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+}
+
+func TestFailureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"dns", dialError(&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}), "DNS"},
+		{"deadline", dialError(context.DeadlineExceeded), "TIMEOUT"},
+		{"net timeout", dialError(timeoutError{}), "TIMEOUT"},
+		{"refused", dialError(os.NewSyscallError("connect", syscall.ECONNREFUSED)), "REFUSED"},
+		{"reset", dialError(os.NewSyscallError("read", syscall.ECONNRESET)), "RESET"},
+		{"network unreachable", dialError(os.NewSyscallError("connect", syscall.ENETUNREACH)), "UNREACHABLE"},
+		{"host unreachable", dialError(os.NewSyscallError("connect", syscall.EHOSTUNREACH)), "UNREACHABLE"},
+		{"unknown authority", &url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, "TLS"},
+		{"hostname mismatch", &url.Error{Op: "Get", URL: "https://example.com", Err: x509.HostnameError{Certificate: &x509.Certificate{}, Host: "example.com"}}, "TLS"},
+		{"verification", &url.Error{Op: "Get", URL: "https://example.com", Err: &tls.CertificateVerificationError{Err: errors.New("expired")}}, "TLS"},
+		{"record header", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, "TLS"},
+		{"remote alert", errors.New("remote error: tls: certificate required"), "TLS"},
+		{"no address", dialError(&noAddressError{host: "example.com", ipVersion: "6"}), "NO IPV6"},
+		{"unrecognized", errors.New("something else"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureReason(tt.err); got != tt.want {
+				t.Errorf("failureReason(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		r    checkResult
+		want string
+	}{
+		{"unexpected status", checkResult{StatusCode: 503, Reason: "HTTP 503"}, "HTTP status"},
+		{"another status", checkResult{StatusCode: 404, Reason: "HTTP 404"}, "HTTP status"},
+		{"body mismatch with a status", checkResult{StatusCode: 200, Reason: "BODY"}, "BODY"},
+		{"network error", checkResult{Reason: "TIMEOUT"}, "TIMEOUT"},
+		{"unclassified error", checkResult{Err: errors.New("something else")}, "other"},
+	}
+	for _, tt := range tests {
+		if got := failureCategory(tt.r); got != tt.want {
+			t.Errorf("%s: failureCategory() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFailureTally(t *testing.T) {
+	tg := newTestTarget()
+	tg.failures = make(failureTally)
+	results := []checkResult{
+		{Reason: "TIMEOUT"},
+		{Connected: true},
+		{Reason: "DNS"},
+		{StatusCode: 503, Reason: "HTTP 503"},
+		{Reason: "TIMEOUT"},
+		{StatusCode: 500, Reason: "HTTP 500"},
+		{Err: errors.New("something else")},
+		{Reason: "TIMEOUT"},
+		{Connected: true},
+	}
+	for i, r := range results {
+		now := testStart.Add(time.Duration(i) * time.Second)
+		r.Time = now
+		tg.observe(r, now)
+	}
+	if got, want := tg.failures.String(), "3 TIMEOUT, 2 HTTP status, 1 DNS, 1 other"; got != want {
+		t.Errorf("tally = %q, want %q", got, want)
+	}
+
+	tg.reset(testStart.Add(time.Minute))
+	if len(tg.failures) != 0 || tg.failures.String() != "" {
+		t.Errorf("tally after reset = %q, want empty", tg.failures.String())
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "context"
+
+// fallbackProber tries each fallback in order when the primary check fails,
+// so a single test URL having its own downtime isn't mistaken for the
+// connection going down
+type fallbackProber struct {
+	Prober
+	fallbacks []Prober
+	urls      []string // url of each fallback, for reporting
+}
+
+// Probe returns the primary result if it succeeded, otherwise the first
+// successful fallback with FallbackURL set, or the primary failure if every
+// fallback failed too
+func (p *fallbackProber) Probe(ctx context.Context) checkResult {
+	result := p.Prober.Probe(ctx)
+	if result.Connected {
+		return result
+	}
+	for i, fallback := range p.fallbacks {
+		if ctx.Err() != nil {
+			break
+		}
+		if r := fallback.Probe(ctx); r.Connected {
+			r.FallbackURL = p.urls[i]
+			return r
+		}
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// codeServer answers every request with code
+func codeServer(code int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+}
+
+// newTestFallback returns a fallbackProber for primary with each of
+// fallbacks tried in order
+func newTestFallback(primary *httptest.Server, fallbacks ...*httptest.Server) *fallbackProber {
+	client := &http.Client{}
+	p := &fallbackProber{Prober: newTestProber(client, primary.URL)}
+	for _, f := range fallbacks {
+		p.fallbacks = append(p.fallbacks, newTestProber(client, f.URL))
+		p.urls = append(p.urls, f.URL)
+	}
+	return p
+}
+
+func TestFallback(t *testing.T) {
+	up, down, alsoUp := codeServer(http.StatusOK), codeServer(http.StatusInternalServerError), codeServer(http.StatusNoContent)
+	defer up.Close()
+	defer down.Close()
+	defer alsoUp.Close()
+
+	tests := []struct {
+		name         string
+		p            *fallbackProber
+		connected    bool
+		fallbackURL  string
+		wantedStatus int
+	}{
+		{"primary up", newTestFallback(up, alsoUp), true, "", http.StatusOK},
+		{"first fallback up", newTestFallback(down, up, alsoUp), true, up.URL, http.StatusOK},
+		{"second fallback up", newTestFallback(down, down, alsoUp), true, alsoUp.URL, http.StatusNoContent},
+		{"all down reports the primary", newTestFallback(down, down), false, "", http.StatusInternalServerError},
+		{"no fallbacks", newTestFallback(down), false, "", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.p.Probe(context.Background())
+			if r.Connected != tt.connected || r.FallbackURL != tt.fallbackURL || r.StatusCode != tt.wantedStatus {
+				t.Errorf("got connected %v, fallback %q, status %d, want %v, %q, %d",
+					r.Connected, r.FallbackURL, r.StatusCode, tt.connected, tt.fallbackURL, tt.wantedStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// flapDetector notices a target changing state too often, which points at
+// something like a loose cable rather than a clean outage
+type flapDetector struct {
+	threshold int           // more changes than this within window is flapping, 0 disables
+	window    time.Duration // how far back changes count
+	changes   []time.Time
+}
+
+// Add records a state change at now and reports whether the target is
+// flapping
+func (f *flapDetector) Add(now time.Time) bool {
+	if f.threshold == 0 {
+		return false
+	}
+	f.changes = append(f.changes, now)
+	return f.Flapping(now)
+}
+
+// Flapping reports whether more than threshold changes happened within the
+// window before now
+func (f *flapDetector) Flapping(now time.Time) bool {
+	if f.threshold == 0 {
+		return false
+	}
+	i := 0
+	for i < len(f.changes) && now.Sub(f.changes[i]) > f.window {
+		i++
+	}
+	f.changes = f.changes[i:]
+	return len(f.changes) > f.threshold
+}
+
+// Count returns the changes within the window as of the last Flapping call
+func (f *flapDetector) Count() int {
+	return len(f.changes)
+}
+
+// Reset forgets every change, keeping the settings
+func (f *flapDetector) Reset() {
+	f.changes = nil
+}
+
+// flapAlert is a target starting to flap
+type flapAlert struct {
+	URL     string
+	Time    time.Time
+	Changes int
+	Window  time.Duration
+}
+
+func (f flapAlert) String() string {
+	return fmt.Sprintf("%s is FLAPPING: %d state changes in %s", f.URL, f.Changes, formatDuration(f.Window))
+}
+
+// flapNotifier is implemented by notifiers that can also report a target
+// starting to flap, which is only passed on with -notify-on flap
+type flapNotifier interface {
+	NotifyFlap(f flapAlert)
+}
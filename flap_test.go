@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlapDetector(t *testing.T) {
+	f := flapDetector{threshold: 3, window: 60 * time.Second}
+	// Each step is a state change at the given second, or a quiet check
+	// with change false
+	steps := []struct {
+		at       int
+		change   bool
+		flapping bool
+		count    int
+	}{
+		{0, true, false, 1},
+		{10, true, false, 2},
+		{20, true, false, 3},
+		{30, true, true, 4}, // a fourth change within a minute
+		{50, false, true, 4},
+		{65, false, false, 3}, // the change at 0s has aged out
+		{71, false, false, 2},
+		{75, true, false, 3},
+		{85, true, false, 3}, // the change at 20s aged out
+		{88, true, true, 4},
+		{200, false, false, 0},
+	}
+	for _, s := range steps {
+		now := testStart.Add(time.Duration(s.at) * time.Second)
+		var got bool
+		if s.change {
+			got = f.Add(now)
+		} else {
+			got = f.Flapping(now)
+		}
+		if got != s.flapping || f.Count() != s.count {
+			t.Errorf("at %ds: flapping %v with %d changes, want %v with %d", s.at, got, f.Count(), s.flapping, s.count)
+		}
+	}
+}
+
+func TestFlapDetectorWindowEdge(t *testing.T) {
+	f := flapDetector{threshold: 1, window: 10 * time.Second}
+	f.Add(testStart)
+	// A change exactly window old still counts
+	if !f.Add(testStart.Add(10 * time.Second)) {
+		t.Error("change exactly one window old was dropped")
+	}
+	if f.Flapping(testStart.Add(11 * time.Second)) {
+		t.Error("change older than the window still counts")
+	}
+}
+
+func TestFlapDetectorDisabled(t *testing.T) {
+	f := flapDetector{window: time.Minute}
+	for i := range 10 {
+		if f.Add(testStart.Add(time.Duration(i) * time.Second)) {
+			t.Fatal("threshold 0 reported flapping")
+		}
+	}
+	if f.Count() != 0 {
+		t.Errorf("threshold 0 kept %d changes, want none", f.Count())
+	}
+}
+
+func TestFlapDetectorReset(t *testing.T) {
+	f := flapDetector{threshold: 2, window: time.Minute}
+	for i := range 3 {
+		f.Add(testStart.Add(time.Duration(i) * time.Second))
+	}
+	f.Reset()
+	if f.Flapping(testStart.Add(3*time.Second)) || f.Count() != 0 {
+		t.Errorf("after Reset: flapping with %d changes, want none", f.Count())
+	}
+	if f.threshold != 2 || f.window != time.Minute {
+		t.Error("Reset changed the settings")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// newGatewayProber checks the gateway over TCP when addr has a port, and
+// with ICMP otherwise
+func newGatewayProber(addr string, timeout time.Duration, ipVersion string, src source) Prober {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return &tcpProber{addr: addr, timeout: timeout, ipVersion: ipVersion, source: src}
+	}
+	return &icmpProber{host: addr, timeout: timeout, ipVersion: ipVersion, source: src}
+}
+
+// diagnosis tells a WAN outage from a LAN one for a failed check, using the
+// gateway check taken alongside it. It returns "" when the check passed or
+// no gateway is monitored.
+func (r checkResult) diagnosis() string {
+	if r.Connected || r.Gateway == nil {
+		return ""
+	}
+	if r.Gateway.Connected {
+		return "ISP/WAN issue"
+	}
+	return "LAN issue"
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// defaultGateway can't detect the gateway without the Linux routing table
+func defaultGateway() (string, error) {
+	return "", errors.New("auto-detection is only supported on Linux; pass -gateway <ip>")
+}
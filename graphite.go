@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// graphiteBuffer is how many checks are held while Carbon is
+	// unreachable, older ones are dropped beyond it
+	graphiteBuffer = 1000
+
+	// graphiteRetry is the pause between attempts to reconnect to Carbon
+	graphiteRetry = 5 * time.Second
+
+	// graphiteFlushTimeout bounds how long Close spends sending what is
+	// still buffered
+	graphiteFlushTimeout = 2 * time.Second
+)
+
+// graphiteSink sends a latency and an up metric in the Carbon plaintext
+// protocol after every check. A background goroutine owns the TCP
+// connection, reconnecting whenever it fails, so a slow or dead Carbon
+// never holds up the checks.
+type graphiteSink struct {
+	addr, prefix string
+	lines        chan string
+	done         chan struct{}
+
+	lastError time.Time
+	dropped   int // checks dropped since lastError
+}
+
+func openGraphite(addr, prefix string) *graphiteSink {
+	g := &graphiteSink{
+		addr:   addr,
+		prefix: prefix,
+		lines:  make(chan string, graphiteBuffer),
+		done:   make(chan struct{}),
+	}
+	go g.run()
+	return g
+}
+
+// Write queues the metrics of a check, dropping them with a rate-limited
+// log message when the buffer is full
+func (g *graphiteSink) Write(url string, r checkResult) error {
+	select {
+	case g.lines <- graphiteLines(g.prefix, url, r):
+	default:
+		if now := time.Now(); now.Sub(g.lastError) >= statsdErrorInterval {
+			slog.Error("graphite buffer full, dropping metrics", "addr", g.addr, "dropped", g.dropped+1)
+			g.lastError, g.dropped = now, 0
+		} else {
+			g.dropped++
+		}
+	}
+	return nil
+}
+
+// Close sends what is still buffered, giving up after graphiteFlushTimeout
+func (g *graphiteSink) Close() error {
+	close(g.lines)
+	select {
+	case <-g.done:
+	case <-time.After(graphiteFlushTimeout):
+	}
+	return nil
+}
+
+// run sends queued lines until Close, holding on to a line that failed to
+// send until the connection is back
+func (g *graphiteSink) run() {
+	defer close(g.done)
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for line := range g.lines {
+		for {
+			if conn == nil {
+				c, err := net.DialTimeout("tcp", g.addr, webhookTimeout)
+				if err != nil {
+					slog.Warn("cannot connect to graphite, retrying", "addr", g.addr, "error", err, "retry", graphiteRetry)
+					time.Sleep(graphiteRetry)
+					continue
+				}
+				conn = c
+			}
+			conn.SetWriteDeadline(time.Now().Add(webhookTimeout))
+			if _, err := conn.Write([]byte(line)); err != nil {
+				slog.Warn("graphite send failed, reconnecting", "addr", g.addr, "error", err)
+				conn.Close()
+				conn = nil
+				continue
+			}
+			break
+		}
+	}
+}
+
+// graphiteLines formats a check as Carbon plaintext lines with the check's
+// Unix time, e.g.
+//
+//	networkcheck.www_google_com.latency_ms 42.1 1714557600
+//	networkcheck.www_google_com.up 1 1714557600
+//
+// A failed check only has the up line.
+func graphiteLines(prefix, url string, r checkResult) string {
+	name := prefix + "." + statsdName(url) + "."
+	ts := r.Time.Unix()
+	var b strings.Builder
+	if r.Connected {
+		fmt.Fprintf(&b, "%slatency_ms %s %d\n", name, strconv.FormatFloat(durationMs(r.Latency), 'f', -1, 64), ts)
+		fmt.Fprintf(&b, "%sup 1 %d\n", name, ts)
+	} else {
+		fmt.Fprintf(&b, "%sup 0 %d\n", name, ts)
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGraphiteLines(t *testing.T) {
+	at := time.Unix(1714557600, 999)
+	tests := []struct {
+		name   string
+		prefix string
+		url    string
+		r      checkResult
+		want   string
+	}{
+		{
+			name:   "connected",
+			prefix: "networkcheck",
+			url:    "https://www.google.com/",
+			r:      checkResult{Time: at, Connected: true, Latency: 42100 * time.Microsecond},
+			want:   "networkcheck.www_google_com.latency_ms 42.1 1714557600\nnetworkcheck.www_google_com.up 1 1714557600\n",
+		},
+		{
+			name:   "failed",
+			prefix: "networkcheck",
+			url:    "https://www.google.com",
+			r:      checkResult{Time: at, Reason: "TIMEOUT", Latency: time.Second},
+			want:   "networkcheck.www_google_com.up 0 1714557600\n",
+		},
+		{
+			name:   "prefix and port",
+			prefix: "home.net",
+			url:    "http://192.168.1.1:8080/status?x=1",
+			r:      checkResult{Time: at, Connected: true, Latency: 3 * time.Millisecond},
+			want:   "home.net.192_168_1_1_8080_status_x_1.latency_ms 3 1714557600\nhome.net.192_168_1_1_8080_status_x_1.up 1 1714557600\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := graphiteLines(tt.prefix, tt.url, tt.r); got != tt.want {
+				t.Errorf("graphiteLines() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphiteSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	at := time.Unix(1714557600, 0)
+	g := openGraphite(ln.Addr().String(), "networkcheck")
+	g.Write("https://example.com", checkResult{Time: at, Connected: true, Latency: 10 * time.Millisecond})
+	g.Write("https://example.com", checkResult{Time: at.Add(time.Second)})
+	g.Close()
+
+	// Close flushes what was queued before hanging up
+	want := "networkcheck.example_com.latency_ms 10 1714557600\n" +
+		"networkcheck.example_com.up 1 1714557600\n" +
+		"networkcheck.example_com.up 0 1714557601\n"
+	select {
+	case got := <-received:
+		if got != want {
+			t.Errorf("carbon received\n%q\nwant\n%q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("carbon received nothing")
+	}
+}
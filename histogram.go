@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultHistogramBuckets are the upper bounds of the -histogram buckets
+const defaultHistogramBuckets = "50ms,100ms,200ms,500ms"
+
+// histogramWidth is the length of the bar of the fullest bucket
+const histogramWidth = 40
+
+// parseHistogramBuckets parses comma-separated, increasing bucket bounds
+func parseHistogramBuckets(s string) ([]time.Duration, error) {
+	var bounds []time.Duration
+	for _, field := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q: %w", field, err)
+		}
+		if d <= 0 || len(bounds) > 0 && d <= bounds[len(bounds)-1] {
+			return nil, fmt.Errorf("invalid bucket %q: bounds must be positive and increasing", field)
+		}
+		bounds = append(bounds, d)
+	}
+	return bounds, nil
+}
+
+// histogram counts the samples in each bucket: below bounds[0], then
+// between consecutive bounds, and finally at or above the last bound, so
+// there is one more count than bounds
+func histogram(samples, bounds []time.Duration) []int {
+	counts := make([]int, len(bounds)+1)
+	for _, s := range samples {
+		i, found := slices.BinarySearch(bounds, s)
+		if found {
+			i++
+		}
+		counts[i]++
+	}
+	return counts
+}
+
+// writeHistogram draws the latency distribution of samples as bars
+// proportional to the count of each bucket
+func writeHistogram(w io.Writer, samples, bounds []time.Duration, latencyUnit string) {
+	counts := histogram(samples, bounds)
+	labels := make([]string, len(counts))
+	for i := range counts {
+		switch {
+		case i == 0:
+			labels[i] = "<" + formatLatency(bounds[0], latencyUnit)
+		case i == len(bounds):
+			labels[i] = formatLatency(bounds[i-1], latencyUnit) + "+"
+		default:
+			labels[i] = formatLatency(bounds[i-1], latencyUnit) + "-" + formatLatency(bounds[i], latencyUnit)
+		}
+	}
+	// Widths count runes, like the padding of %-*s, so µs labels line up
+	labelWidth := 0
+	for _, l := range labels {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(l))
+	}
+	most := slices.Max(counts)
+	for i, n := range counts {
+		bar := 0
+		if most > 0 {
+			bar = (n*histogramWidth + most - 1) / most
+		}
+		fmt.Fprintf(w, "  %-*s %s %d\n", labelWidth, labels[i], strings.Repeat(symbols.bar, bar), n)
+	}
+}
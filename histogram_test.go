@@ -0,0 +1,87 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHistogramBuckets(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		in      string
+		want    []time.Duration
+		wantErr bool
+	}{
+		{defaultHistogramBuckets, []time.Duration{50 * ms, 100 * ms, 200 * ms, 500 * ms}, false},
+		{" 10ms , 1s ", []time.Duration{10 * ms, time.Second}, false},
+		{"250ms", []time.Duration{250 * ms}, false},
+		{"", nil, true},
+		{"10ms,fast", nil, true},
+		{"0s,10ms", nil, true},
+		{"-5ms", nil, true},
+		{"100ms,50ms", nil, true},
+		{"50ms,50ms", nil, true},
+		{"10ms,,20ms", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseHistogramBuckets(tt.in)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("parseHistogramBuckets(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	ms := time.Millisecond
+	bounds := []time.Duration{50 * ms, 100 * ms, 200 * ms}
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		want    []int
+	}{
+		{"no samples", nil, []int{0, 0, 0, 0}},
+		{"one in each", []time.Duration{10 * ms, 70 * ms, 150 * ms, time.Second}, []int{1, 1, 1, 1}},
+		{"a bound belongs to the bucket above", []time.Duration{50 * ms, 100 * ms, 200 * ms}, []int{0, 1, 1, 1}},
+		{"just below a bound", []time.Duration{50*ms - 1, 100*ms - 1}, []int{1, 1, 0, 0}},
+		{"zero latency", []time.Duration{0}, []int{1, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := histogram(tt.samples, bounds); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: histogram() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteHistogram(t *testing.T) {
+	defer func(s symbolSet) { symbols = s }(symbols)
+	symbols = asciiSymbols
+
+	ms := time.Millisecond
+	samples := []time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms, 60 * ms, 300 * ms}
+	var out strings.Builder
+	writeHistogram(&out, samples, []time.Duration{50 * ms, 100 * ms}, "ms")
+	want := "" +
+		"  <50.0ms        " + strings.Repeat("#", 40) + " 4\n" +
+		"  50.0ms-100.0ms " + strings.Repeat("#", 10) + " 1\n" +
+		"  100.0ms+       " + strings.Repeat("#", 10) + " 1\n"
+	if out.String() != want {
+		t.Errorf("writeHistogram() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteHistogramMicroseconds(t *testing.T) {
+	defer func(s symbolSet) { symbols = s }(symbols)
+	symbols = unicodeSymbols
+
+	us := time.Microsecond
+	var out strings.Builder
+	writeHistogram(&out, []time.Duration{100 * us, 600 * us}, []time.Duration{500 * us}, "us")
+	want := "" +
+		"  <500µs " + strings.Repeat("█", 40) + " 1\n" +
+		"  500µs+ " + strings.Repeat("█", 40) + " 1\n"
+	if out.String() != want {
+		t.Errorf("writeHistogram() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMP protocol numbers as used by icmp.ParseMessage
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// icmpSeq is shared by all ICMP probers so concurrent probes never reuse a
+// sequence number
+var icmpSeq atomic.Uint32
+
+// icmpProber checks connectivity by sending an ICMP echo request to a host
+// and measuring the round-trip time of the reply
+type icmpProber struct {
+	host      string
+	timeout   time.Duration
+	ipVersion string
+	source    source
+}
+
+func (p *icmpProber) Probe(ctx context.Context) checkResult {
+	start := time.Now()
+	result := checkResult{Time: start}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, familyNetwork("ip", p.ipVersion), p.host)
+	var dnsErr *net.DNSError
+	if p.ipVersion != "" && errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		err = &noAddressError{host: p.host, ipVersion: p.ipVersion}
+	}
+	if err != nil {
+		result.Err = err
+		result.Reason = failureReason(err)
+		return result
+	}
+	addr := &net.IPAddr{IP: ips[0]}
+
+	network, proto := "ip4:icmp", protocolICMP
+	var echoType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if addr.IP.To4() == nil {
+		network, proto = "ip6:ipv6-icmp", protocolIPv6ICMP
+		echoType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	conn, err := icmp.ListenPacket(network, p.source.listenAddr(addr.IP.To4() == nil))
+	if err != nil {
+		result.Err = err
+		result.Reason = failureReason(err)
+		return result
+	}
+	defer conn.Close()
+
+	id := os.Getpid() & 0xffff
+	seq := int(icmpSeq.Add(1) & 0xffff)
+	msg := icmp.Message{
+		Type: echoType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("networkcheck")},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	// Cancellation unblocks a pending read by moving the deadline up to now
+	conn.SetDeadline(start.Add(p.timeout))
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	if _, err := conn.WriteTo(b, addr); err != nil {
+		result.Err = err
+		result.Reason = failureReason(err)
+		return result
+	}
+
+	// A raw socket sees every ICMP packet for the host, so skip anything
+	// that isn't the reply to this echo
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			result.Err = err
+			result.Reason = failureReason(err)
+			return result
+		}
+		reply, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.ID != id || echo.Seq != seq || peer.String() != addr.String() {
+			continue
+		}
+		result.Latency = time.Since(start)
+		result.RemoteIP = addr.IP
+		result.Connected = true
+		return result
+	}
+}
+
+// checkICMPPrivileges verifies that raw ICMP sockets can be opened, returning
+// an explanatory error when the process lacks the privileges to do so
+func checkICMPPrivileges() error {
+	conn, err := icmp.ListenPacket("ip4:icmp", "")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return errors.New("icmp mode needs raw socket privileges: run as root or grant CAP_NET_RAW (e.g. sudo setcap cap_net_raw+ep networkcheck)")
+		}
+		return fmt.Errorf("icmp mode unavailable: %w", err)
+	}
+	return conn.Close()
+}
+
+// hostFromURL returns the host part of a URL, or the value itself when it
+// is a bare hostname or IP address
+func hostFromURL(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return raw
+}
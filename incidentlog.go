@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// incidentLog appends one line per outage to a journal, separate from the
+// per-check logs. Each line holds the start, end and duration of the outage
+// and the target, separated by tabs, e.g.
+//
+//	2024-05-01T10:00:00Z	2024-05-01T10:01:30Z	1m30s	https://www.google.com
+//
+// An outage still going on at exit has "ongoing" in place of its end.
+type incidentLog struct {
+	f *os.File
+}
+
+// openIncidentLog opens path for appending so history accumulates across
+// restarts
+func openIncidentLog(path string) (*incidentLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &incidentLog{f: f}, nil
+}
+
+// Write appends an outage of url, as of now if it is still ongoing. The file
+// is unbuffered, so each line reaches it as soon as it's written.
+func (l *incidentLog) Write(url string, i incident, now time.Time) error {
+	end := "ongoing"
+	if !i.ongoing() {
+		end = stamp(i.End)
+	}
+	_, err := fmt.Fprintf(l.f, "%s\t%s\t%s\t%s\n", stamp(i.Start), end, i.duration(now).Round(time.Second), url)
+	return err
+}
+
+// WriteOngoing appends the outages still in progress at exit
+func (l *incidentLog) WriteOngoing(targets []*target, now time.Time) error {
+	for _, t := range targets {
+		if n := len(t.incidents); n > 0 && t.incidents[n-1].ongoing() {
+			if err := l.Write(t.url, t.incidents[n-1], now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *incidentLog) Close() error {
+	return l.f.Close()
+}
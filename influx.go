@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// influxLog writes every check as an InfluxDB line-protocol point, either
+// appended to a file or sent as a UDP datagram
+type influxLog struct {
+	w io.WriteCloser
+}
+
+// openInfluxLog sends points to dest when it's a udp://host:port URL and
+// appends them to the file at dest otherwise
+func openInfluxLog(dest string) (*influxLog, error) {
+	if addr, ok := strings.CutPrefix(dest, "udp://"); ok {
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return &influxLog{w: conn}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &influxLog{w: f}, nil
+}
+
+// Write sends one point per check, a single UDP datagram when sending over
+// UDP, which never blocks on a missing listener
+func (l *influxLog) Write(url string, r checkResult) error {
+	_, err := io.WriteString(l.w, influxLine(url, r))
+	return err
+}
+
+func (l *influxLog) Close() error {
+	return l.w.Close()
+}
+
+// influxLine formats a check as a line-protocol point with a nanosecond
+// timestamp, e.g.
+//
+//	networkcheck,url=https://www.google.com connected=1i,latency_ms=42.1 1714557600000000000
+func influxLine(url string, r checkResult) string {
+	var b strings.Builder
+	b.WriteString("networkcheck,url=")
+	b.WriteString(influxTagEscaper.Replace(url))
+	if r.Reason != "" {
+		b.WriteString(",reason=")
+		b.WriteString(influxTagEscaper.Replace(r.Reason))
+	}
+	connected := 0
+	if r.Connected {
+		connected = 1
+	}
+	fmt.Fprintf(&b, " connected=%di", connected)
+	if r.Connected {
+		b.WriteString(",latency_ms=")
+		b.WriteString(strconv.FormatFloat(durationMs(r.Latency), 'f', -1, 64))
+	}
+	if r.StatusCode != 0 {
+		fmt.Fprintf(&b, ",status_code=%di", r.StatusCode)
+	}
+	fmt.Fprintf(&b, " %d\n", r.Time.UnixNano())
+	return b.String()
+}
+
+// influxTagEscaper escapes the characters line protocol treats specially in
+// tag values
+var influxTagEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `)
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInfluxLine(t *testing.T) {
+	at := time.Unix(1700000000, 123456789)
+	tests := []struct {
+		name string
+		url  string
+		r    checkResult
+		want string
+	}{
+		{
+			name: "connected",
+			url:  "https://example.com",
+			r:    checkResult{Time: at, Connected: true, Latency: 42500 * time.Microsecond, StatusCode: 200},
+			want: "networkcheck,url=https://example.com connected=1i,latency_ms=42.5,status_code=200i 1700000000123456789\n",
+		},
+		{
+			name: "failed without a response",
+			url:  "https://example.com",
+			r:    checkResult{Time: at, Reason: "TIMEOUT"},
+			want: "networkcheck,url=https://example.com,reason=TIMEOUT connected=0i 1700000000123456789\n",
+		},
+		{
+			name: "escaped tag values",
+			url:  `https://example.com/a b,c=d\e`,
+			r:    checkResult{Time: at, Reason: "HTTP 503", StatusCode: 503},
+			want: `networkcheck,url=https://example.com/a\ b\,c\=d\\e,reason=HTTP\ 503 connected=0i,status_code=503i 1700000000123456789` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := influxLine(tt.url, tt.r); got != tt.want {
+				t.Errorf("influxLine() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// noAddressError is returned when a check is restricted to one IP family
+// and the host has no address in it
+type noAddressError struct {
+	host      string
+	ipVersion string
+}
+
+func (e *noAddressError) Error() string {
+	return fmt.Sprintf("no IPv%s address for %s", e.ipVersion, e.host)
+}
+
+// parseIPVersion validates an -ip-version value, returning "4", "6" or ""
+// for either family
+func parseIPVersion(value string) (string, error) {
+	switch value {
+	case "auto":
+		return "", nil
+	case "4", "6":
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid -ip-version %q: must be auto, 4 or 6", value)
+}
+
+// familyNetwork restricts a network such as "tcp" or "ip" to ipVersion
+func familyNetwork(network, ipVersion string) string {
+	return network + ipVersion
+}
+
+// dialFamily dials addr over network restricted to ipVersion, reporting a
+// noAddressError when the host has no address in that family
+func dialFamily(ctx context.Context, d *net.Dialer, network, addr, ipVersion string) (net.Conn, error) {
+	conn, err := d.DialContext(ctx, familyNetwork(network, ipVersion), addr)
+	var addrErr *net.AddrError
+	if ipVersion != "" && errors.As(err, &addrErr) && addrErr.Err == "no suitable address found" {
+		host, _, _ := net.SplitHostPort(addr)
+		return nil, &noAddressError{host: host, ipVersion: ipVersion}
+	}
+	return conn, err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// jsonLog appends newline-delimited JSON records of every check to a file
+type jsonLog struct {
+	f   *os.File
+	enc *json.Encoder
+}
+
+// openJSONLog opens path for appending so repeated runs accumulate history
+func openJSONLog(path string) (*jsonLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLog{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Write appends the result of a check against url. The file is unbuffered,
+// so each record reaches it as soon as it's written.
+func (l *jsonLog) Write(url string, r checkResult) error {
+	return l.enc.Encode(newCheckRecord(url, r))
+}
+
+// WriteSummary appends the exit summary for all targets
+func (l *jsonLog) WriteSummary(targets []*target) error {
+	return l.enc.Encode(newSummaryRecord(targets))
+}
+
+func (l *jsonLog) Close() error {
+	return l.f.Close()
+}
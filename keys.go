@@ -0,0 +1,44 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// readKeys switches the terminal on stdin to cbreak mode, where keypresses
+// arrive one at a time without being echoed while Ctrl+C still interrupts,
+// and sends them on the returned channel. restore puts the terminal back
+// the way it was. It fails when stdin isn't a terminal.
+func readKeys() (keys <-chan byte, restore func(), err error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, nil, unix.ENOTTY
+	}
+	old, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, nil, err
+	}
+	cbreak := *old
+	cbreak.Lflag &^= unix.ICANON | unix.ECHO
+	cbreak.Cc[unix.VMIN], cbreak.Cc[unix.VTIME] = 1, 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &cbreak); err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan byte, 8)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if n, err := os.Stdin.Read(buf); err != nil {
+				return
+			} else if n == 1 {
+				ch <- buf[0]
+			}
+		}
+	}()
+	return ch, func() { unix.IoctlSetTermios(fd, ioctlSetTermios, old) }, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// readKeys always fails, cbreak mode needs a Unix terminal, which leaves
+// the key bindings disabled
+func readKeys() (keys <-chan byte, restore func(), err error) {
+	return nil, nil, errors.New("key bindings are only supported on Unix terminals")
+}
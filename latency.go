@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// latencyUnits are the accepted -latency-unit values
+var latencyUnits = []string{"auto", "us", "ms", "s"}
+
+// formatLatency formats a latency in unit, one of latencyUnits. auto picks
+// microseconds below a millisecond, seconds from a second up and
+// milliseconds in between.
+func formatLatency(d time.Duration, unit string) string {
+	if unit == "auto" {
+		switch {
+		case d < time.Millisecond:
+			unit = "us"
+		case d >= time.Second:
+			unit = "s"
+		default:
+			unit = "ms"
+		}
+	}
+	switch unit {
+	case "us":
+		return fmt.Sprintf("%d%s", d.Round(time.Microsecond).Microseconds(), symbols.micro)
+	case "s":
+		return fmt.Sprintf("%.3fs", d.Seconds())
+	}
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatLatency(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		unit string
+		want string
+	}{
+		{0, "auto", "0µs"},
+		{999 * time.Microsecond, "auto", "999µs"},
+		{time.Millisecond, "auto", "1.0ms"},
+		{42350 * time.Microsecond, "auto", "42.4ms"},
+		{999 * time.Millisecond, "auto", "999.0ms"},
+		{time.Second, "auto", "1.000s"},
+		{2500 * time.Millisecond, "auto", "2.500s"},
+		{1500 * time.Nanosecond, "us", "2µs"},
+		{42 * time.Millisecond, "us", "42000µs"},
+		{250 * time.Microsecond, "ms", "0.2ms"},
+		{2 * time.Second, "ms", "2000.0ms"},
+		{42 * time.Millisecond, "s", "0.042s"},
+		{90 * time.Second, "s", "90.000s"},
+	}
+	for _, tt := range tests {
+		if got := formatLatency(tt.d, tt.unit); got != tt.want {
+			t.Errorf("formatLatency(%s, %q) = %q, want %q", tt.d, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLatencyASCII(t *testing.T) {
+	defer func(s symbolSet) { symbols = s }(symbols)
+	symbols = asciiSymbols
+
+	if got := formatLatency(250*time.Microsecond, "auto"); got != "250us" {
+		t.Errorf("formatLatency(250µs, auto) = %q with -ascii, want 250us", got)
+	}
+	if got := formatLatency(42*time.Millisecond, "us"); got != "42000us" {
+		t.Errorf("formatLatency(42ms, us) = %q with -ascii, want 42000us", got)
+	}
+}
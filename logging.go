@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// levelNotice is above every -log-level, for outcomes that are reported
+// whatever the level, such as alert deliveries
+const levelNotice = slog.LevelError + 4
+
+// newLogger builds the stderr logger for -log-level and -log-json
+func newLogger(level string, asJSON bool) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid -log-level %q: must be debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{
+		Level: l,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if lvl, ok := a.Value.Any().(slog.Level); ok && a.Key == slog.LevelKey && lvl == levelNotice {
+				a.Value = slog.StringValue("NOTICE")
+			}
+			return a
+		},
+	}
+	if asJSON {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+}
+
+// logResult logs a check at debug when it passed and at warn when it failed
+func logResult(url string, r checkResult) {
+	if r.Connected {
+		slog.Debug("check passed", "url", url, "latency", r.Latency, "status_code", r.StatusCode)
+		return
+	}
+	attrs := []any{"url", url, "status_code", r.StatusCode}
+	if r.Reason != "" {
+		attrs = append(attrs, "reason", r.Reason)
+	}
+	if r.Err != nil {
+		attrs = append(attrs, "error", r.Err)
+	}
+	slog.Warn("check failed", attrs...)
+}
+
+// logChange logs a state transition at info, and the outage it ended if
+// any
+func logChange(c stateChange) {
+	slog.Info("state changed", "url", c.URL, "from", c.From, "to", c.To, "previous_duration", c.Previous)
+	if c.recovered() {
+		slog.Info("recovered", "url", c.URL, "outage", c.Previous)
+	}
+}
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,173 +31,1513 @@ var (
 	defaultTimeout       = 5 * time.Second
 )
 
+// urlList collects the values of a repeatable, comma-separated -url flag
+type urlList []string
+
+func (u *urlList) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urlList) Values() []string {
+	return *u
+}
+
+func (u *urlList) Reset() {
+	*u = nil
+}
+
+func (u *urlList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*u = append(*u, v)
+		}
+	}
+	return nil
+}
+
+// headerList collects the values of a repeatable -header flag
+type headerList []string
+
+func (h *headerList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerList) Values() []string {
+	return *h
+}
+
+func (h *headerList) Reset() {
+	*h = nil
+}
+
+func (h *headerList) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
 func main() {
+	os.Exit(run())
+}
+
+// run monitors the targets until told to stop and returns the exit code,
+// leaving os.Exit to main so deferred cleanup runs first
+func run() int {
 	// Define command line flags
+	var testURLs, tcpTargets urlList
+	var headers headerList
 	checkIntervalFlag := flag.Duration("interval", defaultCheckInterval, "Interval between connection checks (e.g. 2s, 1m)")
-	testURLFlag := flag.String("url", defaultTestURL, "URL to test connection against")
-	timeoutFlag := flag.Duration("timeout", defaultTimeout, "HTTP request timeout")
+	flag.Var(&testURLs, "url", "URL to test connection against (repeatable or comma-separated, default "+defaultTestURL+")")
+	timeoutFlag := flag.Duration("timeout", defaultTimeout, "Time allowed for each check, including any -retries")
+	attemptTimeoutFlag := flag.Duration("attempt-timeout", 0, "Time allowed for each attempt of a check with -retries, at most -timeout (0 = -timeout)")
+	formatFlag := flag.String("format", "tty", "Output format: tty (live display) or json (one JSON object per check)")
+	modeFlag := flag.String("mode", "http", "Check mode: http (GET the URL), icmp (ping the URL's host, needs root), tcp (connect to -target) or dns (resolve the URL's host)")
+	dnsServerFlag := flag.String("dns-server", "", "Resolver host:port for -mode dns to query instead of the system's, e.g. 1.1.1.1:53")
+	flag.Var(&tcpTargets, "target", "host:port to connect to in tcp mode (repeatable or comma-separated)")
+	targetsFileFlag := flag.String("targets-file", "", "Read more targets from this file, one per line; blank lines and # comments are ignored")
+	countFlag := flag.Int("count", 0, "Number of checks to perform before exiting, including the first (0 = unlimited)")
+	backoffFlag := flag.Bool("backoff", false, "Double the check interval after each consecutive failure, up to -max-interval")
+	jitterFlag := flag.Float64("jitter", 0, "Randomize each check interval by up to this percentage either way, e.g. 20")
+	maxIntervalFlag := flag.Duration("max-interval", time.Minute, "Longest check interval -backoff grows to")
+	summaryIntervalFlag := flag.Duration("summary-interval", 0, "Print a rollup of every target this often, e.g. 1h (0 = only at exit)")
+	summaryResetFlag := flag.Bool("summary-reset", false, "Reset the statistics after each -summary-interval rollup so every period stands alone")
+	durationFlag := flag.Duration("duration", 0, "Stop monitoring after this long, e.g. 30m (0 = run forever)")
+	shutdownTimeoutFlag := flag.Duration("shutdown-timeout", 2*time.Second, "How long to wait at exit for notifications still being delivered")
+	throughputFlag := flag.Bool("throughput", false, "Measure download speed by fetching a payload on every check (uses data; the default -interval becomes 1m)")
+	downloadURLFlag := flag.String("download-url", "", "Payload URL for -throughput (default the -url)")
+	downloadSizeFlag := flag.Int64("download-size", defaultDownloadSize, "Most bytes -throughput downloads per check")
+	quorumFlag := flag.Int("quorum", 0, "With several -url targets, count the connection as up when at least this many of them are (0 = off)")
+	var fallbackURLs urlList
+	flag.Var(&fallbackURLs, "fallback-url", "URL to try when a check of -url fails, before counting it as down (repeatable or comma-separated, tried in order)")
+	burstFlag := flag.Int("burst", 1, "Probes sent each check; the share lost is reported as packet loss, most meaningful in icmp and tcp modes")
+	burstSpacingFlag := flag.Duration("burst-spacing", 50*time.Millisecond, "Time between the probes of a -burst")
+	retriesFlag := flag.Int("retries", 0, "Retry a failed check up to this many times within the same interval before counting it as failed")
+	failuresThresholdFlag := flag.Int("failures-threshold", 1, "Consecutive failed checks before a target is shown as disconnected")
+	rotateFlag := flag.Bool("rotate", false, "Check one target per interval in turn instead of all of them at once, to spread the load")
+	warmupFlag := flag.Int("warmup", 0, "Checks at startup whose results are shown but left out of the statistics, while caches are cold")
+	recoveryThresholdFlag := flag.Int("recovery-threshold", 1, "Consecutive successful checks before a disconnected target is shown as connected again")
+	methodFlag := flag.String("method", http.MethodGet, "HTTP method for checks: GET or HEAD (HEAD skips downloading the body)")
+	maxBodyReadFlag := flag.Int64("max-body-read", 4<<10, "Most bytes of each HTTP response body to read before discarding the rest, to bound bandwidth on metered connections")
+	userAgentFlag := flag.String("user-agent", "", "User-Agent header for HTTP checks (default Go's)")
+	flag.Var(&headers, "header", "Extra request header for HTTP checks as \"Key: Value\" (repeatable)")
+	basicAuthFlag := flag.String("basic-auth", "", "Credentials for HTTP basic auth as user:pass")
+	bearerFlag := flag.String("bearer", "", "Bearer token sent in the Authorization header")
+	proxyFlag := flag.String("proxy", "", "Proxy URL for HTTP checks (default from HTTP_PROXY/HTTPS_PROXY)")
+	gatewayFlag := flag.String("gateway", "", "Also check this gateway to tell LAN from WAN outages: an address to ping, host:port to connect to, or auto for the default route")
+	ipVersionFlag := flag.String("ip-version", "auto", "IP family to check over: auto, 4 or 6")
+	interfaceFlag := flag.String("interface", "", "Send checks out through this network interface, e.g. eth1 (Linux only)")
+	sourceIPFlag := flag.String("source-ip", "", "Send checks from this local address")
+	noRedirectsFlag := flag.Bool("no-redirects", false, "Don't follow HTTP redirects, checking the redirect status against -expect-status instead")
+	pinIPFlag := flag.Bool("pin-ip", false, "Resolve each target's host once at startup and connect to that address on every check, leaving DNS out of the latency")
+	noKeepAliveFlag := flag.Bool("no-keepalive", false, "Open a new connection for every HTTP check so each one includes DNS, connect and TLS setup (more load on the target)")
+	clientCertFlag := flag.String("client-cert", "", "PEM client certificate to present for mutual TLS, requires -client-key")
+	clientKeyFlag := flag.String("client-key", "", "PEM private key of the -client-cert")
+	caCertFlag := flag.String("ca-cert", "", "PEM CA certificates to verify HTTPS servers with instead of the system roots")
+	insecureFlag := flag.Bool("insecure", false, "Skip TLS certificate verification for HTTPS checks")
+	expectBodyFlag := flag.String("expect-body", "", "Only count a check as connected if the response body contains this text")
+	expectBodyRegexpFlag := flag.String("expect-body-regexp", "", "Only count a check as connected if the response body matches this regular expression")
+	traceFlag := flag.Bool("trace", false, "Break HTTP latency down into DNS, connect, TLS and time-to-first-byte")
+	verboseFlag := flag.Bool("verbose", false, "Describe every HTTP exchange on stderr: request, status line, selected headers, server address, phase timings and the full error chain")
+	certWarnDaysFlag := flag.Int("cert-warn-days", 14, "Warn when an HTTPS target's certificate expires within this many days")
+	latencyUnitFlag := flag.String("latency-unit", "auto", "Unit latencies are shown in: us, ms, s or auto to pick one per value")
+	timezoneFlag := flag.String("timezone", "local", "Time zone for every displayed and logged timestamp: local, UTC or a name such as America/New_York")
+	timestampFormatFlag := flag.String("timestamp-format", "15:04:05", "Go time layout for timestamps in the display, e.g. \"2006-01-02 15:04:05\"")
+	latencyWarnFlag := flag.Duration("latency-warn", 0, "Show a successful check slower than this as DEGRADED (0 = disabled)")
+	latencyGoodFlag := flag.Duration("latency-good", 100*time.Millisecond, "Show latencies below this in green")
+	latencyBadFlag := flag.Duration("latency-bad", 300*time.Millisecond, "Show latencies above this in red, and those in between in yellow")
+	trendDeadbandFlag := flag.Float64("trend-deadband", 10, "Percentage either side of the recent average latency shown as steady (→) rather than rising (↑) or falling (↓)")
+	flapThresholdFlag := flag.Int("flap-threshold", 5, "Warn that a target is FLAPPING when it changes state more than this many times within -flap-window (0 = never)")
+	flapWindowFlag := flag.Duration("flap-window", 10*time.Minute, "How far back state changes count towards -flap-threshold")
+	flapQuietFlag := flag.Bool("flap-quiet", false, "Hold back state-change notifications for a target while it is flapping")
+	spikeAlertsFlag := flag.Bool("spike-alerts", false, "Alert when a check is much slower than the target's usual latency, and notify with -notify-on spike")
+	spikeSigmaFlag := flag.Float64("spike-sigma", 3, "Standard deviations above the mean latency over the -window that count as a -spike-alerts spike")
+	spikeWarmupFlag := flag.Int("spike-warmup", 20, "Successful checks in the -window needed to establish the latency baseline before -spike-alerts fires")
+	sustainedLatencyFlag := flag.Duration("sustained-latency", 0, "Alert when latency stays above this for -sustained-duration, and notify with -notify-on sustained (0 to disable)")
+	sustainedDurationFlag := flag.Duration("sustained-duration", 30*time.Second, "How long latency must stay above -sustained-latency before alerting")
+	ewmaAlphaFlag := flag.Float64("ewma-alpha", defaultEWMAAlpha, "Weight of each new latency in the smoothed average, from just above 0 (smoothest) to 1 (no smoothing)")
+	expectStatusFlag := flag.String("expect-status", defaultExpectStatus, "HTTP status codes and ranges that count as connected, e.g. 200-299,401")
+	displayFlag := flag.String("display", "", "Display style: tui (redraw in place), dashboard (interactive, with latency charts) or log (one line per check); defaults to log when stdout is not a terminal")
+	onceFlag := flag.Bool("once", false, "Check once, print a single line (or JSON object) and exit with status 0 if connected or 1 if not; meant for cron jobs and monitoring scripts")
+	retrySummaryFlag := flag.Bool("retry-summary", false, "Count failed checks by cause, e.g. TIMEOUT or DNS, and list the breakdown in the exit summary")
+	failOnAnyDownFlag := flag.Bool("fail-on-any-down", false, "Exit with status 1 if any target went down during the run, not just at exit")
+	quietFlag := flag.Bool("quiet", false, "Only print a line when a target changes state, plus the exit summary")
+	compactFlag := flag.Bool("compact", false, "Show every target's status on a single line that is rewritten in place")
+	noColorFlag := flag.Bool("no-color", false, "Disable colored output")
+	asciiFlag := flag.Bool("ascii", false, "Draw with ASCII only, e.g. [OK] and [DOWN] instead of ✓ and ✗, for terminals that can't show Unicode (the default when the locale isn't UTF-8)")
+	connectedSymbolFlag := flag.String("connected-symbol", "", "Symbol shown for a connected target (default ✓, or [OK] with -ascii)")
+	disconnectedSymbolFlag := flag.String("disconnected-symbol", "", "Symbol shown for a disconnected target (default ✗, or [DOWN] with -ascii)")
+	connectedTextFlag := flag.String("connected-text", "", "Status text shown for a connected target (default CONNECTED)")
+	disconnectedTextFlag := flag.String("disconnected-text", "", "Status text shown for a disconnected target (default DISCONNECTED)")
+	forceColorFlag := flag.Bool("force-color", false, "Keep colored output even when stdout is not a terminal")
+	notifyFlag := flag.Bool("notify", false, "Show a desktop notification when a target connects or disconnects")
+	bellFlag := flag.Bool("bell", false, "Ring the terminal bell when a target disconnects")
+	bellOnRecoveryFlag := flag.Bool("bell-on-recovery", false, "With -bell, also ring it when a target recovers")
+	notifyOnFlag := flag.String("notify-on", defaultNotifyOn, "Events passed on to every notifier, comma-separated: "+strings.Join(notifyEventNames, ", ")+"; PagerDuty incidents are resolved on recovery even without up")
+	silentSuccessFlag := flag.Bool("silent-success", false, "Don't alert when a target comes back up, only about problems; short for leaving up out of -notify-on")
+	notifyCooldownFlag := flag.Duration("notify-cooldown", 0, "Minimum time between desktop, Discord or bell alerts, to quiet flapping")
+	webhookFlag := flag.String("webhook", "", "POST a JSON payload to this URL when a target connects or disconnects")
+	slackWebhookFlag := flag.String("slack-webhook", "", "Slack incoming-webhook URL to post state changes to")
+	slackTemplateFlag := flag.String("slack-template", defaultSlackTemplate, "Slack message template with {status}, {url}, {time} and {duration} placeholders")
+	telegramTokenFlag := flag.String("telegram-token", "", "Telegram bot token to send state changes with, requires -telegram-chat-id")
+	telegramChatIDFlag := flag.String("telegram-chat-id", "", "Telegram chat to send state changes to")
+	telegramTemplateFlag := flag.String("telegram-template", defaultTelegramTemplate, "Telegram message template with {status}, {url}, {time} and {duration} placeholders")
+	discordWebhookFlag := flag.String("discord-webhook", "", "Discord webhook URL to post an embed to on state changes")
+	pagerDutyKeyFlag := flag.String("pagerduty-key", "", "PagerDuty Events API v2 routing key to trigger an incident when a target goes down and resolve it on recovery")
+	histogramFlag := flag.Bool("histogram", false, "Print a histogram of each target's latencies in the exit summary")
+	histogramBucketsFlag := flag.String("histogram-buckets", defaultHistogramBuckets, "Upper bounds of the -histogram buckets, increasing and comma-separated; one more bucket holds the rest")
+	mqttBrokerFlag := flag.String("mqtt-broker", "", "MQTT broker to publish to, e.g. tcp://localhost:1883")
+	mqttTopicFlag := flag.String("mqtt-topic", "networkcheck", "MQTT topic to publish to; <topic>/availability says whether the monitor is running")
+	mqttUsernameFlag := flag.String("mqtt-username", "", "Username for the -mqtt-broker")
+	mqttPasswordFlag := flag.String("mqtt-password", "", "Password for the -mqtt-broker")
+	mqttQoSFlag := flag.Int("mqtt-qos", 0, "MQTT quality of service: 0, 1 or 2")
+	mqttPublishFlag := flag.String("mqtt-publish", "change", "What to publish to MQTT: change (state changes) or check (every check)")
+	mqttFormatFlag := flag.String("mqtt-format", "json", "MQTT payload format: json or plain (\"<url> <status>\")")
+	eventSocketFlag := flag.String("event-socket", "", "Stream state changes as JSON lines to every client connected to a Unix socket at this path")
+	windowFlag := flag.Int("window", 60, "Number of recent checks the rolling success rate covers")
+	metricsAddrFlag := flag.String("metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9090")
+	otlpEndpointFlag := flag.String("otlp-endpoint", "", "Export OpenTelemetry metrics over OTLP/HTTP to this collector, e.g. http://localhost:4318 (needs a build with -tags otel)")
+	otlpTracesFlag := flag.Bool("otlp-traces", false, "Also export a span per check to -otlp-endpoint, with the -trace phases as events")
+	serveFlag := flag.String("serve", "", "Serve /status (JSON) and /healthz on this address, e.g. :8080")
+	csvFlag := flag.String("csv", "", "Append one CSV row per check to this file")
+	influxFlag := flag.String("influx", "", "Write every check as an InfluxDB line-protocol point to this file, or to udp://host:port")
+	statsdFlag := flag.String("statsd", "", "Send a latency gauge and success/failure counters to this StatsD host:port over UDP after every check")
+	dogstatsdFlag := flag.Bool("statsd-dogstatsd", false, "Tag -statsd metrics with the target in DogStatsD format instead of putting it in the metric name")
+	graphiteFlag := flag.String("graphite", "", "Send latency and up metrics to this Graphite/Carbon host:port over TCP after every check")
+	graphitePrefixFlag := flag.String("graphite-prefix", "networkcheck", "Prefix of the -graphite metric names")
+	stateFileFlag := flag.String("state-file", "", "Save accumulated statistics to this file at exit and continue from them on the next run")
+	resetFlag := flag.Bool("reset", false, "Ignore any saved -state-file and start the statistics from scratch")
+	incidentLogFlag := flag.String("incident-log", "", "Append one line per outage, with its start, end and duration, to this file")
+	logFileFlag := flag.String("log-file", "", "Append one JSON record per check, and a summary at exit, to this file")
+	logLevelFlag := flag.String("log-level", "error", "Least severe log messages written to stderr: debug, info, warn or error")
+	logJSONFlag := flag.Bool("log-json", false, "Write log messages to stderr as JSON instead of text")
+	configFlag := flag.String("config", "", "Load options from this YAML file, keyed by flag name; command-line flags take precedence")
+	printConfigFlag := flag.Bool("print-config", false, "Print the effective configuration as YAML and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\nEvery flag can also be set with an environment variable, e.g. %s for -interval.\n", envName("interval"))
+		fmt.Fprintln(flag.CommandLine.Output(), "Command-line flags take precedence over the environment, which takes precedence over -config.")
+		fmt.Fprintln(flag.CommandLine.Output(), "Send SIGHUP to reload -config and -targets-file; the interval, thresholds and targets apply without a restart.")
+	}
 	flag.Parse()
 
+	if err := applyEnv(flag.CommandLine); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// Options set on the command line or in the environment, which
+	// reloading -config must not override
+	pinned := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { pinned[f.Name] = true })
+	if *configFlag != "" {
+		cfg, err := loadConfig(*configFlag)
+		if err == nil {
+			err = cfg.apply(flag.CommandLine)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *printConfigFlag {
+		if err := printConfig(os.Stdout, flag.CommandLine); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	logger, err := newLogger(*logLevelFlag, *logJSONFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	slog.SetDefault(logger)
+
+	if *formatFlag != "tty" && *formatFlag != "json" {
+		fmt.Fprintf(os.Stderr, "invalid -format %q: must be tty or json\n", *formatFlag)
+		return 2
+	}
+
+	switch *modeFlag {
+	case "http", "icmp", "tcp", "dns":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -mode %q: must be http, icmp, tcp or dns\n", *modeFlag)
+		return 2
+	}
+	jsonOutput := *formatFlag == "json"
+	if jsonOutput && *compactFlag {
+		fmt.Fprintln(os.Stderr, "-compact and -format json conflict, use only one")
+		return 2
+	}
+
+	// -once is a single check reported on a single line, whatever the
+	// display and interval options say
+	if *onceFlag {
+		*countFlag = 1
+		*displayFlag = "log"
+		*quietFlag, *compactFlag = false, false
+		*rotateFlag = false
+	}
+
+	if *quietFlag && *compactFlag {
+		fmt.Fprintln(os.Stderr, "-quiet and -compact conflict, use only one")
+		return 2
+	}
+
+	switch *displayFlag {
+	case "":
+		if *quietFlag {
+			*displayFlag = "log"
+		} else if isTerminal(os.Stdout) {
+			*displayFlag = "tui"
+		} else {
+			*displayFlag = "log"
+		}
+	case "log":
+	case "tui", "dashboard":
+		if *quietFlag {
+			fmt.Fprintln(os.Stderr, "-quiet only works with -display log")
+			return 2
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -display %q: must be tui, dashboard or log\n", *displayFlag)
+		return 2
+	}
+
+	// The color package already honors NO_COLOR and disables itself when
+	// stdout isn't a terminal, the flags override that either way
+	if *noColorFlag {
+		color.NoColor = true
+	} else if *forceColorFlag {
+		color.NoColor = false
+	}
+	if *asciiFlag || asciiLocale() {
+		symbols = asciiSymbols
+	}
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, custom := range []struct {
+		name          string
+		value, symbol *string
+	}{
+		{"connected-symbol", connectedSymbolFlag, &symbols.ok},
+		{"disconnected-symbol", disconnectedSymbolFlag, &symbols.fail},
+		{"connected-text", connectedTextFlag, &symbols.connected},
+		{"disconnected-text", disconnectedTextFlag, &symbols.disconnected},
+	} {
+		if !explicit[custom.name] {
+			continue
+		}
+		if strings.TrimSpace(*custom.value) == "" {
+			fmt.Fprintf(os.Stderr, "invalid -%s: must not be empty\n", custom.name)
+			return 2
+		}
+		*custom.symbol = *custom.value
+	}
+
+	*methodFlag = strings.ToUpper(*methodFlag)
+	if *methodFlag != http.MethodGet && *methodFlag != http.MethodHead {
+		fmt.Fprintf(os.Stderr, "invalid -method %q: must be GET or HEAD\n", *methodFlag)
+		return 2
+	}
+
+	requestHeader, err := parseHeaders(headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -header: %v\n", err)
+		return 2
+	}
+	if *userAgentFlag != "" {
+		requestHeader.Set("User-Agent", *userAgentFlag)
+	}
+	if *basicAuthFlag != "" && *bearerFlag != "" {
+		fmt.Fprintln(os.Stderr, "-basic-auth and -bearer conflict, use only one")
+		return 2
+	}
+	if *basicAuthFlag != "" {
+		user, pass, ok := strings.Cut(*basicAuthFlag, ":")
+		if !ok {
+			fmt.Fprintln(os.Stderr, "invalid -basic-auth: must be in user:pass form")
+			return 2
+		}
+		requestHeader.Set("Authorization", basicAuth(user, pass))
+	}
+	if *bearerFlag != "" {
+		requestHeader.Set("Authorization", "Bearer "+*bearerFlag)
+	}
+
+	if *windowFlag < 1 {
+		fmt.Fprintf(os.Stderr, "invalid -window %d: must be at least 1\n", *windowFlag)
+		return 2
+	}
+
+	isExpectedStatus, err := parseExpectStatus(*expectStatusFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -expect-status: %v\n", err)
+		return 2
+	}
+
+	bodyMatches, err := newBodyMatcher(*expectBodyFlag, *expectBodyRegexpFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -expect-body-regexp: %v\n", err)
+		return 2
+	}
+
+	if *jitterFlag < 0 || *jitterFlag > 100 {
+		fmt.Fprintf(os.Stderr, "invalid -jitter %g: must be between 0 and 100\n", *jitterFlag)
+		return 2
+	}
+
+	if !slices.Contains(latencyUnits, *latencyUnitFlag) {
+		fmt.Fprintf(os.Stderr, "invalid -latency-unit %q: must be auto, us, ms or s\n", *latencyUnitFlag)
+		return 2
+	}
+
+	if err := setTimezone(*timezoneFlag); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	timeLayout = *timestampFormatFlag
+
+	if *latencyGoodFlag > *latencyBadFlag {
+		fmt.Fprintf(os.Stderr, "invalid -latency-good %s: must not exceed -latency-bad %s\n", *latencyGoodFlag, *latencyBadFlag)
+		return 2
+	}
+
+	if *trendDeadbandFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -trend-deadband %g: must not be negative\n", *trendDeadbandFlag)
+		return 2
+	}
+
+	if *ewmaAlphaFlag <= 0 || *ewmaAlphaFlag > 1 {
+		fmt.Fprintf(os.Stderr, "invalid -ewma-alpha %g: must be above 0 and at most 1\n", *ewmaAlphaFlag)
+		return 2
+	}
+
+	if *flapThresholdFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -flap-threshold %d: must not be negative\n", *flapThresholdFlag)
+		return 2
+	}
+
+	if *spikeSigmaFlag <= 0 {
+		fmt.Fprintf(os.Stderr, "invalid -spike-sigma %g: must be positive\n", *spikeSigmaFlag)
+		return 2
+	}
+	if *spikeAlertsFlag && *spikeWarmupFlag > *windowFlag {
+		fmt.Fprintf(os.Stderr, "invalid -spike-warmup %d: can't exceed -window %d\n", *spikeWarmupFlag, *windowFlag)
+		return 2
+	}
+
+	if *sustainedLatencyFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -sustained-latency %s: must not be negative\n", *sustainedLatencyFlag)
+		return 2
+	}
+	if *sustainedDurationFlag <= 0 {
+		fmt.Fprintf(os.Stderr, "invalid -sustained-duration %s: must be positive\n", *sustainedDurationFlag)
+		return 2
+	}
+
+	if *maxBodyReadFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -max-body-read %d: must not be negative\n", *maxBodyReadFlag)
+		return 2
+	}
+
+	if *burstFlag < 1 {
+		fmt.Fprintf(os.Stderr, "invalid -burst %d: must be at least 1\n", *burstFlag)
+		return 2
+	}
+	if *burstFlag > 1 {
+		if *burstSpacingFlag < 0 {
+			fmt.Fprintf(os.Stderr, "invalid -burst-spacing %s: must not be negative\n", *burstSpacingFlag)
+			return 2
+		}
+		if spread := time.Duration(*burstFlag-1) * *burstSpacingFlag; spread >= *timeoutFlag {
+			fmt.Fprintf(os.Stderr, "-burst %d spaced %s apart takes %s to send, longer than -timeout %s\n", *burstFlag, *burstSpacingFlag, spread, *timeoutFlag)
+			return 2
+		}
+		if *retriesFlag > 0 {
+			fmt.Fprintln(os.Stderr, "-burst and -retries can't be combined, a lost probe counts as packet loss instead of being retried")
+			return 2
+		}
+		if *throughputFlag {
+			fmt.Fprintln(os.Stderr, "-burst doesn't work with -throughput")
+			return 2
+		}
+	}
+
+	if *retriesFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -retries %d: must not be negative\n", *retriesFlag)
+		return 2
+	}
+
+	if *attemptTimeoutFlag < 0 || *attemptTimeoutFlag > *timeoutFlag {
+		fmt.Fprintf(os.Stderr, "invalid -attempt-timeout %s: must be between 0 and -timeout (%s)\n", *attemptTimeoutFlag, *timeoutFlag)
+		return 2
+	}
+	// Each attempt gets the whole -timeout unless -attempt-timeout splits it
+	attemptTimeout := *timeoutFlag
+	if *attemptTimeoutFlag > 0 {
+		attemptTimeout = *attemptTimeoutFlag
+	}
+
+	if *failuresThresholdFlag < 1 {
+		fmt.Fprintf(os.Stderr, "invalid -failures-threshold %d: must be at least 1\n", *failuresThresholdFlag)
+		return 2
+	}
+	if *warmupFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -warmup %d: must not be negative\n", *warmupFlag)
+		return 2
+	}
+
+	var histogramBounds []time.Duration
+	if *histogramFlag {
+		bounds, err := parseHistogramBuckets(*histogramBucketsFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -histogram-buckets: %v\n", err)
+			return 2
+		}
+		histogramBounds = bounds
+	}
+
+	if *recoveryThresholdFlag < 1 {
+		fmt.Fprintf(os.Stderr, "invalid -recovery-threshold %d: must be at least 1\n", *recoveryThresholdFlag)
+		return 2
+	}
+
+	// targetURLs lists the targets to monitor: -url, or -target in tcp
+	// mode, plus any -targets-file
+	targetURLs := func() ([]string, error) {
+		urls := slices.Clone([]string(testURLs))
+		if *modeFlag == "tcp" {
+			urls = slices.Clone([]string(tcpTargets))
+		}
+		if *targetsFileFlag != "" {
+			fileTargets, err := readTargetsFile(*targetsFileFlag, *modeFlag)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -targets-file: %w", err)
+			}
+			urls = append(urls, fileTargets...)
+		}
+		if *modeFlag == "tcp" && len(urls) == 0 {
+			return nil, errors.New("tcp mode requires at least one -target host:port")
+		}
+		if len(urls) == 0 {
+			urls = []string{defaultTestURL}
+		}
+		return urls, nil
+	}
+	urls, err := targetURLs()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *quorumFlag < 0 || *quorumFlag > len(urls) {
+		fmt.Fprintf(os.Stderr, "invalid -quorum %d: must be between 1 and the number of targets (%d), or 0 to disable it\n", *quorumFlag, len(urls))
+		return 2
+	}
+
+	if *throughputFlag {
+		if *modeFlag != "http" {
+			fmt.Fprintln(os.Stderr, "-throughput only works in http mode")
+			return 2
+		}
+		if *downloadSizeFlag < 1 {
+			fmt.Fprintf(os.Stderr, "invalid -download-size %d: must be at least 1\n", *downloadSizeFlag)
+			return 2
+		}
+		intervalSet := false
+		flag.Visit(func(f *flag.Flag) { intervalSet = intervalSet || f.Name == "interval" })
+		if !intervalSet {
+			*checkIntervalFlag = defaultThroughputInterval
+		}
+		fmt.Fprintf(os.Stderr, "warning: -throughput downloads up to %d bytes per target every %s\n", *downloadSizeFlag, *checkIntervalFlag)
+	}
+
+	if len(fallbackURLs) > 0 && (*modeFlag != "http" || *throughputFlag) {
+		fmt.Fprintln(os.Stderr, "-fallback-url only works in http mode without -throughput")
+		return 2
+	}
+
+	if *otlpEndpointFlag != "" {
+		if u, err := url.Parse(*otlpEndpointFlag); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			fmt.Fprintf(os.Stderr, "invalid -otlp-endpoint %q: must be an http:// or https:// URL without a path\n", *otlpEndpointFlag)
+			return 2
+		}
+	} else if *otlpTracesFlag {
+		fmt.Fprintln(os.Stderr, "-otlp-traces needs -otlp-endpoint")
+		return 2
+	}
+
+	if *notifyFlag {
+		if err := setupDesktop(); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot use -notify: %v\n", err)
+			return 2
+		}
+	}
+
+	// Phase timings are part of the verbose details, and of -otlp-traces
+	// spans
+	if *otlpTracesFlag && *modeFlag == "http" {
+		*traceFlag = true
+	}
+	if *verboseFlag {
+		*traceFlag = true
+	}
+
+	if *insecureFlag {
+		fmt.Fprintln(os.Stderr, "warning: -insecure is set, TLS certificates will not be verified")
+	}
+	ipVersion, err := parseIPVersion(*ipVersionFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	src, err := parseSource(*interfaceFlag, *sourceIPFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *dnsServerFlag != "" {
+		if *modeFlag != "dns" {
+			fmt.Fprintln(os.Stderr, "-dns-server only works in dns mode")
+			return 2
+		}
+		if _, _, err := net.SplitHostPort(*dnsServerFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -dns-server %q: must be host:port\n", *dnsServerFlag)
+			return 2
+		}
+	}
+	resolver := newResolver(*dnsServerFlag, attemptTimeout, src)
+	transportCfg := transportConfig{insecure: *insecureFlag, ipVersion: ipVersion, source: src, noKeepAlive: *noKeepAliveFlag}
+	if *clientCertFlag != "" || *clientKeyFlag != "" {
+		if *clientCertFlag == "" || *clientKeyFlag == "" {
+			fmt.Fprintln(os.Stderr, "-client-cert and -client-key must be set together")
+			return 2
+		}
+		cert, err := tls.LoadX509KeyPair(*clientCertFlag, *clientKeyFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot load client certificate: %v\n", err)
+			return 2
+		}
+		transportCfg.clientCerts = []tls.Certificate{cert}
+	}
+	if *caCertFlag != "" {
+		if transportCfg.rootCAs, err = loadCAPool(*caCertFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot load -ca-cert: %v\n", err)
+			return 2
+		}
+	}
+	if *pinIPFlag {
+		if *modeFlag != "http" && *modeFlag != "tcp" {
+			fmt.Fprintln(os.Stderr, "-pin-ip only works in http and tcp mode")
+			return 2
+		}
+		transportCfg.pins = newPinnedHosts(ipVersion, *timeoutFlag)
+	}
+	// pinTargets resolves the host of every target not yet pinned with
+	// -pin-ip
+	pinTargets := func(urls []string) error {
+		if transportCfg.pins == nil {
+			return nil
+		}
+		for _, u := range urls {
+			host := hostFromURL(u)
+			if *modeFlag == "tcp" {
+				host, _, _ = net.SplitHostPort(u)
+			}
+			if err := transportCfg.pins.pin(host); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := pinTargets(urls); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *proxyFlag != "" {
+		if transportCfg.proxy, err = parseProxyURL(*proxyFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -proxy: %v\n", err)
+			return 2
+		}
+	}
+	transport := newTransport(transportCfg)
+
 	// Create HTTP client with timeout
 	client := &http.Client{
-		Timeout: *timeoutFlag,
-	}
-
-	// Setup signal catching for graceful exit
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Clear screen and hide cursor
-	fmt.Print("\033[H\033[2J\033[?25l")
-	defer fmt.Print("\033[?25h") // Show cursor when done
-
-	fmt.Println("Internet Connection Monitor")
-	fmt.Printf("Testing connection to: %s\n", *testURLFlag)
-	fmt.Println("Press Ctrl+C to exit")
-	fmt.Println("----------------------------")
-
-	// Create ticker for periodic checks
-	ticker := time.NewTicker(*checkIntervalFlag)
-	defer ticker.Stop()
-
-	// Success and failure formatters
-	success := color.New(color.FgGreen, color.Bold)
-	failure := color.New(color.FgRed, color.Bold)
-	info := color.New(color.FgCyan)
-
-	// Status tracking
-	var lastStatus bool
-	var statusChangeTime time.Time
-	var downtime time.Duration
-	var uptime time.Duration
-	
-	// Latency statistics
-	var minLatency time.Duration = -1
-	var maxLatency time.Duration
-	var totalLatency time.Duration
-	var latencyCount int
+		Timeout:   attemptTimeout,
+		Transport: transport,
+	}
+	if *noRedirectsFlag {
+		// Judge the redirect response itself against -expect-status, which
+		// also catches captive portals redirecting to their login page
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
 
-	// Initial status check
-	var latency time.Duration
-	lastStatus, latency = checkConnection(client, *testURLFlag)
-	statusChangeTime = time.Now()
-	
-	// Update latency stats if connected
-	if lastStatus && latency > 0 {
-		minLatency = latency
-		maxLatency = latency
-		totalLatency = latency
-		latencyCount = 1
-	}
-	
-	displayStatus(lastStatus, success, failure, info, 0, latency)
+	newHTTPProber := func(u string) *httpProber {
+		p := &httpProber{
+			client:           client,
+			timeout:          attemptTimeout,
+			method:           *methodFlag,
+			url:              u,
+			header:           requestHeader,
+			isExpectedStatus: isExpectedStatus,
+			trace:            *traceFlag,
+			bodyMatches:      bodyMatches,
+			maxBodyRead:      *maxBodyReadFlag,
+		}
+		if *verboseFlag {
+			p.verbose = os.Stderr
+		}
+		return p
+	}
 
-	// Main loop
-	for {
-		select {
-		case <-ticker.C:
-			currentStatus, latency := checkConnection(client, *testURLFlag)
-			now := time.Now()
-			duration := now.Sub(statusChangeTime)
-
-			// Update uptime/downtime tracking - simplified logic
-			if currentStatus {
-				uptime += duration
-				
-				// Update latency statistics
-				if latency > 0 {
-					if minLatency < 0 || latency < minLatency {
-						minLatency = latency
+	newTarget := func(u string) *target {
+		t := &target{
+			url:               u,
+			failureThreshold:  *failuresThresholdFlag,
+			recoveryThreshold: *recoveryThresholdFlag,
+			warmup:            *warmupFlag,
+			latencyWarn:       *latencyWarnFlag,
+			recent:            newWindow(*windowFlag),
+			latencyEWMA:       ewma{alpha: *ewmaAlphaFlag},
+			flaps:             flapDetector{threshold: *flapThresholdFlag, window: *flapWindowFlag},
+		}
+		if *retrySummaryFlag {
+			t.failures = make(failureTally)
+		}
+		switch *modeFlag {
+		case "http":
+			if *throughputFlag {
+				download := u
+				if *downloadURLFlag != "" {
+					download = *downloadURLFlag
+				}
+				t.prober = &throughputProber{
+					client:           client,
+					timeout:          attemptTimeout,
+					url:              download,
+					header:           requestHeader,
+					isExpectedStatus: isExpectedStatus,
+					maxBytes:         *downloadSizeFlag,
+				}
+				break
+			}
+			t.prober = newHTTPProber(u)
+			if len(fallbackURLs) > 0 {
+				fallback := &fallbackProber{Prober: t.prober, urls: fallbackURLs}
+				for _, f := range fallbackURLs {
+					fallback.fallbacks = append(fallback.fallbacks, newHTTPProber(f))
+				}
+				t.prober = fallback
+			}
+		case "icmp":
+			t.prober = &icmpProber{host: hostFromURL(u), timeout: attemptTimeout, ipVersion: ipVersion, source: src}
+		case "tcp":
+			addr := u
+			if transportCfg.pins != nil {
+				addr = transportCfg.pins.addr(u)
+			}
+			t.prober = &tcpProber{addr: addr, timeout: attemptTimeout, ipVersion: ipVersion, source: src}
+		case "dns":
+			t.prober = &dnsProber{host: hostFromURL(u), timeout: attemptTimeout, ipVersion: ipVersion, resolver: resolver}
+		}
+		if *burstFlag > 1 {
+			t.prober = &burstProber{Prober: t.prober, count: *burstFlag, spacing: *burstSpacingFlag, budget: *timeoutFlag}
+		}
+		if *retriesFlag > 0 {
+			t.prober = &retryProber{Prober: t.prober, retries: *retriesFlag, budget: *timeoutFlag}
+		}
+		return t
+	}
+
+	targets := make([]*target, len(urls))
+	for i, u := range urls {
+		targets[i] = newTarget(u)
+	}
+	if *stateFileFlag != "" && !*resetFlag {
+		state, err := loadState(*stateFileFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot load state file: %v\n", err)
+			return 1
+		}
+		if state != nil {
+			for _, t := range targets {
+				if saved, ok := state.Targets[t.url]; ok {
+					t.restore(saved)
+				}
+			}
+		}
+	}
+
+	// The gateway is checked alongside the targets to tell LAN outages
+	// from WAN ones, but isn't a target itself
+	var gateway *target
+	if *gatewayFlag != "" {
+		addr := *gatewayFlag
+		if addr == "auto" {
+			if addr, err = defaultGateway(); err != nil {
+				fmt.Fprintf(os.Stderr, "cannot detect the default gateway: %v\n", err)
+				return 1
+			}
+		}
+		gateway = &target{
+			url:               addr,
+			prober:            newGatewayProber(addr, *timeoutFlag, ipVersion, src),
+			failureThreshold:  *failuresThresholdFlag,
+			recoveryThreshold: *recoveryThresholdFlag,
+			recent:            newWindow(*windowFlag),
+			latencyEWMA:       ewma{alpha: *ewmaAlphaFlag},
+		}
+	}
+	icmpGateway := false
+	if gateway != nil {
+		_, icmpGateway = gateway.prober.(*icmpProber)
+	}
+	if *modeFlag == "icmp" || icmpGateway {
+		if err := checkICMPPrivileges(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	// Output and alert options are all checked before any sink is opened
+	if *graphiteFlag != "" {
+		if _, _, err := net.SplitHostPort(*graphiteFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -graphite %q: must be host:port\n", *graphiteFlag)
+			return 2
+		}
+	}
+	if *mqttBrokerFlag != "" {
+		if *mqttQoSFlag < 0 || *mqttQoSFlag > 2 {
+			fmt.Fprintf(os.Stderr, "invalid -mqtt-qos %d: must be 0, 1 or 2\n", *mqttQoSFlag)
+			return 2
+		}
+		if *mqttPublishFlag != "change" && *mqttPublishFlag != "check" {
+			fmt.Fprintf(os.Stderr, "invalid -mqtt-publish %q: must be change or check\n", *mqttPublishFlag)
+			return 2
+		}
+		if *mqttFormatFlag != "json" && *mqttFormatFlag != "plain" {
+			fmt.Fprintf(os.Stderr, "invalid -mqtt-format %q: must be json or plain\n", *mqttFormatFlag)
+			return 2
+		}
+		if *mqttTopicFlag == "" {
+			fmt.Fprintln(os.Stderr, "-mqtt-topic must not be empty")
+			return 2
+		}
+	}
+	notifyOn, err := parseNotifyEvents(*notifyOnFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -notify-on %q: %v\n", *notifyOnFlag, err)
+		return 2
+	}
+	if *silentSuccessFlag {
+		delete(notifyOn, "up")
+	}
+	if (*telegramTokenFlag == "") != (*telegramChatIDFlag == "") {
+		fmt.Fprintln(os.Stderr, "-telegram-token and -telegram-chat-id must be set together")
+		return 2
+	}
+
+	// Setup signal catching for graceful exit, cancelling any checks still
+	// in flight
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	// Sinks closed by the teardown once monitoring stops, and the poster
+	// whose deliveries it waits for
+	var sinks teardown
+	posts := newPoster()
+
+	// Ordered teardown however monitoring stops, including a sink failing
+	// to open: cancel checks still in flight, close every sink so files are
+	// flushed, then give pending notifications up to -shutdown-timeout to
+	// go out
+	defer func() {
+		stop()
+		sinks.close()
+		if !posts.wait(*shutdownTimeoutFlag) {
+			slog.Warn("gave up on pending notifications", "timeout", *shutdownTimeoutFlag)
+		}
+	}()
+
+	// Files every check result is appended to
+	var checkLogs []checkLog
+	if *csvFlag != "" {
+		l, err := openCSVLog(*csvFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot open CSV log: %v\n", err)
+			return 1
+		}
+		if l.movedTo != "" {
+			fmt.Fprintf(os.Stderr, "%s had different columns, so it was moved to %s and a new CSV log started\n", *csvFlag, l.movedTo)
+		}
+		sinks.add(l)
+		checkLogs = append(checkLogs, l)
+	}
+	if *influxFlag != "" {
+		l, err := openInfluxLog(*influxFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot open InfluxDB output: %v\n", err)
+			return 1
+		}
+		sinks.add(l)
+		checkLogs = append(checkLogs, l)
+	}
+	if *statsdFlag != "" {
+		l, err := openStatsd(*statsdFlag, *dogstatsdFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot open StatsD output: %v\n", err)
+			return 1
+		}
+		sinks.add(l)
+		checkLogs = append(checkLogs, l)
+	}
+	if *graphiteFlag != "" {
+		l := openGraphite(*graphiteFlag, strings.Trim(*graphitePrefixFlag, "."))
+		sinks.add(l)
+		checkLogs = append(checkLogs, l)
+	}
+	var mqttOut *mqttPublisher
+	if *mqttBrokerFlag != "" {
+		mqttOut = newMQTTPublisher(mqttConfig{
+			broker:   *mqttBrokerFlag,
+			topic:    *mqttTopicFlag,
+			username: *mqttUsernameFlag,
+			password: *mqttPasswordFlag,
+			qos:      byte(*mqttQoSFlag),
+			onCheck:  *mqttPublishFlag == "check",
+			plain:    *mqttFormatFlag == "plain",
+		})
+		sinks.add(mqttOut)
+		checkLogs = append(checkLogs, mqttOut)
+	}
+	var jsonLogger *jsonLog
+	if *logFileFlag != "" {
+		var err error
+		if jsonLogger, err = openJSONLog(*logFileFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot open log file: %v\n", err)
+			return 1
+		}
+		sinks.add(jsonLogger)
+		checkLogs = append(checkLogs, jsonLogger)
+	}
+
+	var incidents *incidentLog
+	if *incidentLogFlag != "" {
+		var err error
+		if incidents, err = openIncidentLog(*incidentLogFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot open incident log: %v\n", err)
+			return 1
+		}
+		sinks.add(incidents)
+	}
+
+	var promMetrics *metrics
+	if *metricsAddrFlag != "" {
+		if promMetrics, err = startMetrics(*metricsAddrFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot start metrics server: %v\n", err)
+			return 1
+		}
+		sinks.add(closerFunc(promMetrics.Shutdown))
+	}
+
+	var otlp *otlpExporter
+	if *otlpEndpointFlag != "" {
+		if otlp, err = startOTLP(*otlpEndpointFlag, *otlpTracesFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot start OTLP export: %v\n", err)
+			return 1
+		}
+		sinks.add(closerFunc(otlp.Shutdown))
+	}
+
+	var statusSrv *statusServer
+	if *serveFlag != "" {
+		if statusSrv, err = startStatusServer(*serveFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot start status server: %v\n", err)
+			return 1
+		}
+		sinks.add(closerFunc(statusSrv.Shutdown))
+	}
+
+	// Alerts sent when a target changes state
+	alerts := &dispatcher{on: notifyOn}
+	if *notifyFlag {
+		alerts.add(newDesktopNotifier(*notifyCooldownFlag))
+	}
+	// The bell only makes sense on a terminal, it's dropped silently when
+	// stderr is redirected
+	if *bellFlag && isTerminal(os.Stderr) {
+		alerts.add(&bellNotifier{w: os.Stderr, onRecovery: *bellOnRecoveryFlag, cooldown: *notifyCooldownFlag})
+	}
+	if *webhookFlag != "" {
+		alerts.add(&webhookNotifier{url: *webhookFlag, poster: posts})
+	}
+	if *slackWebhookFlag != "" {
+		alerts.add(&slackNotifier{url: *slackWebhookFlag, template: *slackTemplateFlag, poster: posts})
+	}
+	if *telegramTokenFlag != "" {
+		alerts.add(&telegramNotifier{token: *telegramTokenFlag, chatID: *telegramChatIDFlag, template: *telegramTemplateFlag, poster: posts})
+	}
+	if *pagerDutyKeyFlag != "" {
+		alerts.add(newPagerDutyNotifier(*pagerDutyKeyFlag, posts))
+	}
+	if *discordWebhookFlag != "" {
+		alerts.add(&discordNotifier{url: *discordWebhookFlag, poster: posts, cooldown: *notifyCooldownFlag})
+	}
+	if mqttOut != nil {
+		alerts.add(mqttOut)
+	}
+	if *eventSocketFlag != "" {
+		events, err := listenEventSocket(*eventSocketFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot open event socket: %v\n", err)
+			return 1
+		}
+		sinks.add(events)
+		alerts.add(events)
+	}
+
+	// Signals that dump, reset or reload the stats and config, or resize
+	// the display, where the platform has them
+	dumpChan, resetChan, hupChan, winchChan := notifyControlSignals()
+
+	var enc *json.Encoder
+	if jsonOutput {
+		// A reader going away should shut us down cleanly rather than kill
+		// the process
+		ignoreSIGPIPE()
+		enc = json.NewEncoder(os.Stdout)
+	}
+
+	disp := newDisplay(*displayFlag == "log")
+	disp.compact = *compactFlag
+	disp.quiet = *quietFlag
+	disp.trace = *traceFlag
+	disp.certWarnDays = *certWarnDaysFlag
+	disp.latencyUnit = *latencyUnitFlag
+	disp.latencyGood, disp.latencyBad = *latencyGoodFlag, *latencyBadFlag
+	disp.trendDeadband = *trendDeadbandFlag
+	disp.gateway = gateway
+	disp.quorum = *quorumFlag
+	var dashCommands <-chan string
+	var dashDone <-chan struct{}
+	if *displayFlag == "dashboard" && !jsonOutput && !*compactFlag && !*onceFlag {
+		disp.dash = newDashboard(disp)
+		dashCommands, dashDone = disp.dash.commands, disp.dash.done
+	}
+	if !jsonOutput && !*onceFlag {
+		var details []string
+		if *modeFlag == "http" {
+			details = append(details, "Proxy: "+describeProxy(transport, urls[0]))
+		}
+		if transportCfg.pins != nil {
+			details = append(details, "Pinned: "+transportCfg.pins.String())
+		}
+		disp.start(strings.Join(urls, ","), details...)
+		defer disp.stop()
+	}
+
+	// Timer for the next check, rearmed after every round since -backoff
+	// varies the interval
+	timer := time.NewTimer(*checkIntervalFlag)
+	defer timer.Stop()
+
+	// Wait before the next round of checks, worked out as each round is
+	// reported
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	delay := *checkIntervalFlag
+
+	// publishStatus refreshes what -serve answers with
+	publishStatus := func(now time.Time) {
+		if statusSrv != nil {
+			statusSrv.update(newStatusSnapshot(targets, *quorumFlag, now))
+		}
+	}
+
+	// report records one round of results and prints them, returning false
+	// once stdout is gone (e.g. broken pipe) and there is nothing left to
+	// report to
+	report := func(checked []*target, results []checkResult) bool {
+		now := time.Now()
+		if gateway != nil {
+			gw := results[len(checked)]
+			gateway.observe(gw, now)
+			for i := range checked {
+				results[i].Gateway = &gw
+			}
+		}
+		for i, t := range checked {
+			logResult(t.url, results[i])
+			if spike, ok := t.detectSpike(results[i], *spikeSigmaFlag, *spikeWarmupFlag, *latencyUnitFlag); *spikeAlertsFlag && ok {
+				slog.Warn("latency spike", "url", t.url, "latency", spike.Latency, "baseline", spike.Mean, "stddev", spike.StdDev)
+				if !jsonOutput {
+					disp.summary(fmt.Sprintf("[%s] %s\n", clock(spike.Time), disp.warning.Sprint(symbols.warn+" "+spike.String())))
+				}
+				alerts.spike(spike)
+			}
+			if *sustainedLatencyFlag > 0 {
+				alert, fire, cleared := t.trackSustained(results[i], *sustainedLatencyFlag, *sustainedDurationFlag, *latencyUnitFlag)
+				switch {
+				case fire:
+					slog.Warn("sustained high latency", "url", t.url, "latency", alert.Latency, "threshold", alert.Threshold, "streak", alert.Streak)
+					if !jsonOutput {
+						disp.summary(fmt.Sprintf("[%s] %s\n", clock(alert.Time), disp.warning.Sprint(symbols.warn+" "+alert.String())))
 					}
-					if latency > maxLatency {
-						maxLatency = latency
+					alerts.sustained(alert)
+				case cleared:
+					slog.Info("latency recovered", "url", t.url, "latency", results[i].Latency)
+					if !jsonOutput {
+						disp.summary(fmt.Sprintf("[%s] %s\n", clock(results[i].Time), disp.fast.Sprintf("%s latency back under %s", t.url, formatLatency(*sustainedLatencyFlag, *latencyUnitFlag))))
 					}
-					totalLatency += latency
-					latencyCount++
 				}
-			} else {
-				downtime += duration
 			}
-			
-			// Update tracking variables
-			statusChangeTime = now
-			if currentStatus != lastStatus {
-				lastStatus = currentStatus
+			if change := t.observe(results[i], now); change != nil {
+				logChange(*change)
+				if !jsonOutput {
+					disp.change(*change)
+				}
+				wasFlapping := t.flaps.Flapping(now)
+				flapping := t.flaps.Add(now)
+				if flapping && !wasFlapping {
+					flap := flapAlert{URL: t.url, Time: now, Changes: t.flaps.Count(), Window: *flapWindowFlag}
+					slog.Warn("flapping", "url", t.url, "changes", flap.Changes, "window", flap.Window)
+					if !jsonOutput {
+						disp.summary(fmt.Sprintf("[%s] %s\n", clock(now), disp.warning.Sprint(symbols.warn+" "+flap.String())))
+					}
+					alerts.flap(flap)
+				}
+				if !flapping || !*flapQuietFlag {
+					alerts.change(*change)
+				}
+				if outage, ok := t.lastOutage(); ok && incidents != nil && change.recovered() {
+					if err := incidents.Write(t.url, outage, now); err != nil {
+						slog.Error("incident log write failed", "error", err)
+					}
+				}
+			}
+			if promMetrics != nil {
+				promMetrics.observe(t)
+			}
+			if otlp != nil {
+				otlp.observe(t)
+			}
+			for _, l := range checkLogs {
+				if err := l.Write(t.url, results[i]); err != nil {
+					slog.Error("log write failed", "error", err)
+				}
+			}
+			if jsonOutput {
+				if err := enc.Encode(newCheckRecord(t.url, results[i])); err != nil {
+					return false
+				}
+			}
+		}
+		publishStatus(now)
+		delay = *checkIntervalFlag
+		backedOff := false
+		if *backoffFlag {
+			delay = nextInterval(targets, *checkIntervalFlag, *maxIntervalFlag)
+			backedOff = delay > *checkIntervalFlag
+		}
+		delay = jitter(rng, delay, *jitterFlag)
+		if !jsonOutput {
+			disp.nextCheck = time.Time{}
+			if backedOff {
+				disp.nextCheck = now.Add(delay)
 			}
+			disp.update(targets, checked)
+		}
+		return true
+	}
 
-			displayStatus(currentStatus, success, failure, info, duration, latency)
+	// finish prints the exit summary, shared by every way the monitor stops
+	finish := func() {
+		// The summary goes to the normal screen, after the display is
+		// torn down
+		disp.stop()
+		if !*onceFlag {
+			printSummary(targets, enc, *latencyUnitFlag, histogramBounds)
+		}
+		if jsonLogger != nil {
+			if err := jsonLogger.WriteSummary(targets); err != nil {
+				slog.Error("log write failed", "error", err)
+			}
+		}
+		if incidents != nil {
+			if err := incidents.WriteOngoing(targets, time.Now()); err != nil {
+				slog.Error("incident log write failed", "error", err)
+			}
+		}
+		if *stateFileFlag != "" {
+			if err := saveState(*stateFileFlag, targets, time.Now()); err != nil {
+				slog.Error("cannot save state file", "error", err)
+			}
+		}
+	}
 
-		case <-sigChan:
-			// Clean up and exit
-			fmt.Println("\n\nExiting Connection Monitor")
-			fmt.Printf("Total uptime: %s\n", formatDuration(uptime))
-			fmt.Printf("Total downtime: %s\n", formatDuration(downtime))
-			if latencyCount > 0 {
-				fmt.Printf("Min latency: %s\n", minLatency)
-				fmt.Printf("Max latency: %s\n", maxLatency)
-				fmt.Printf("Avg latency: %s\n", totalLatency/time.Duration(latencyCount))
+	// Checks left to perform, zero means run until interrupted
+	remaining := *countFlag
+
+	// Deadline for the whole run, a nil channel never fires
+	var deadline <-chan time.Time
+	if *durationFlag > 0 {
+		deadline = time.After(*durationFlag)
+	}
+
+	// Index of the target the next -rotate round checks
+	next := 0
+
+	// round checks every target once, or with -rotate the next one in turn,
+	// and reports the results, returning false once the monitor should stop
+	round := func() bool {
+		checked := targets
+		if *rotateFlag {
+			checked = []*target{targets[next%len(targets)]}
+			next = (next + 1) % len(targets)
+			// A status older than two full rotations no longer counts
+			for _, t := range targets {
+				t.staleAfter = 2 * time.Duration(len(targets)) * max(delay, *checkIntervalFlag)
 			}
+		}
+		all := checked
+		if gateway != nil {
+			all = append(all[:len(all):len(all)], gateway)
+		}
+		results := checkAll(ctx, all)
+		if ctx.Err() != nil {
+			// Interrupted mid-check, the results only hold cancellation
+			// errors and must not count as failures
+			finish()
+			return false
+		}
+		if !report(checked, results) {
+			return false
+		}
+		if remaining > 0 {
+			remaining--
+			if remaining == 0 {
+				finish()
+				return false
+			}
+		}
+		timer.Reset(delay)
+		return true
+	}
+
+	// Initial status check
+	if !round() {
+		return exitCode(targets, *failOnAnyDownFlag, *quorumFlag)
+	}
+
+	// Periodic summaries, a nil channel never fires
+	var summaryTick <-chan time.Time
+	if *summaryIntervalFlag > 0 {
+		summaryTicker := time.NewTicker(*summaryIntervalFlag)
+		defer summaryTicker.Stop()
+		summaryTick = summaryTicker.C
+	}
+
+	// resetAll zeroes every target's statistics, e.g. on SIGUSR2
+	resetAll := func() {
+		now := time.Now()
+		for _, t := range targets {
+			t.reset(now)
+		}
+		publishStatus(now)
+		msg := fmt.Sprintf("\n[%s] stats reset\n", clock(now))
+		if jsonOutput {
+			fmt.Fprint(os.Stderr, msg)
+		} else {
+			disp.announce(msg)
+		}
+	}
+
+	// reload applies a changed -config and -targets-file without losing the
+	// statistics of targets that remain, on the main goroutine like every
+	// other change to the targets
+	reload := func() {
+		now := time.Now()
+		// Failures are reported like the success below, on stderr when
+		// stdout carries JSON
+		failed := func(err error) {
+			slog.Error("reload failed", "error", err)
+			msg := fmt.Sprintf("\n[%s] reload failed: %v\n", clock(now), err)
+			if jsonOutput {
+				fmt.Fprint(os.Stderr, msg)
+			} else {
+				disp.announce(msg)
+			}
+		}
+		var restart []string
+		if *configFlag != "" {
+			var revert func()
+			var err error
+			restart, revert, err = reloadConfig(*configFlag, flag.CommandLine, pinned)
+			if err == nil {
+				err = validateLive(*checkIntervalFlag, *failuresThresholdFlag, *recoveryThresholdFlag, *jitterFlag, *latencyGoodFlag, *latencyBadFlag)
+				if err != nil {
+					revert()
+				}
+			}
+			if err != nil {
+				failed(err)
+				return
+			}
+		}
+		for _, key := range restart {
+			slog.Warn("option changed but needs a restart to apply", "option", key)
+		}
+
+		urls, err := targetURLs()
+		if err == nil {
+			err = pinTargets(urls)
+		}
+		if err != nil {
+			failed(err)
+			return
+		}
+		existing := make(map[string]*target, len(targets))
+		for _, t := range targets {
+			existing[t.url] = t
+		}
+		reloaded := make([]*target, len(urls))
+		for i, u := range urls {
+			if t, ok := existing[u]; ok {
+				reloaded[i] = t
+			} else {
+				reloaded[i] = newTarget(u)
+			}
+		}
+		targets = reloaded
+		for _, t := range targets {
+			t.failureThreshold, t.recoveryThreshold = *failuresThresholdFlag, *recoveryThresholdFlag
+			t.latencyWarn = *latencyWarnFlag
+		}
+		if *quorumFlag > len(targets) {
+			slog.Warn("-quorum exceeds the number of targets", "quorum", *quorumFlag, "targets", len(targets))
+		}
+		publishStatus(now)
+
+		delay = *checkIntervalFlag
+		timer.Reset(delay)
+		msg := fmt.Sprintf("\n[%s] configuration reloaded, %d targets, checking every %s\n", clock(now), len(targets), *checkIntervalFlag)
+		if len(restart) > 0 {
+			msg += "restart to apply: " + strings.Join(restart, ", ") + "\n"
+		}
+		slog.Info("configuration reloaded", "targets", len(targets), "interval", *checkIntervalFlag)
+		if jsonOutput {
+			fmt.Fprint(os.Stderr, msg)
 			return
 		}
+		disp.latencyGood, disp.latencyBad = *latencyGoodFlag, *latencyBadFlag
+		disp.trendDeadband = *trendDeadbandFlag
+		disp.resize(targets)
+		disp.announce(msg)
 	}
-}
 
-// checkConnection tests the internet connection and returns connection status and latency
-func checkConnection(client *http.Client, url string) (bool, time.Duration) {
-	start := time.Now()
-	resp, err := client.Get(url)
-	if err != nil {
-		return false, 0
+	// Paused skips checks until resumed, and the time spent paused is left
+	// out of every target's uptime and downtime
+	paused := false
+	var pausedAt time.Time
+	togglePause := func() {
+		paused = !paused
+		now := time.Now()
+		if paused {
+			pausedAt = now
+		} else {
+			for _, t := range targets {
+				t.resume(now.Sub(pausedAt))
+			}
+		}
+		if !jsonOutput {
+			disp.paused = paused
+			disp.update(targets, targets)
+		}
 	}
-	defer resp.Body.Close()
-	latency := time.Since(start)
-	return resp.StatusCode >= 200 && resp.StatusCode < 300, latency
-}
 
-// displayStatus prints the current connection status, duration, and network latency if connected.
-func displayStatus(connected bool, success, failure, info *color.Color, duration time.Duration, latency time.Duration) {
-	// Move cursor to status line (row 4, clear line)
-	fmt.Print("\033[4;0H\033[K")
+	// Main loop
+	for {
+		select {
+		case <-timer.C:
+			if paused {
+				timer.Reset(delay)
+				continue
+			}
+			if !round() {
+				return exitCode(targets, *failOnAnyDownFlag, *quorumFlag)
+			}
+
+		case <-ctx.Done():
+			// Clean up and exit
+			finish()
+			return exitCode(targets, *failOnAnyDownFlag, *quorumFlag)
+
+		case <-dumpChan:
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "\n[%s] Stats snapshot\n", clock(time.Now()))
+			writeStats(&buf, targets, *latencyUnitFlag)
+			if jsonOutput {
+				os.Stderr.Write(buf.Bytes())
+			} else {
+				disp.announce(buf.String())
+			}
+
+		case <-resetChan:
+			resetAll()
+
+		case <-hupChan:
+			reload()
+
+		case key := <-disp.keys:
+			if key == ' ' {
+				togglePause()
+			}
+
+		case cmd := <-dashCommands:
+			switch cmd {
+			case dashboardPause:
+				togglePause()
+			case dashboardReset:
+				resetAll()
+			}
+
+		case <-dashDone:
+			// Quit from the dashboard's keybindings
+			finish()
+			return exitCode(targets, *failOnAnyDownFlag, *quorumFlag)
+
+		case now := <-summaryTick:
+			if jsonOutput {
+				summary := newSummaryRecord(targets)
+				summary.Type = "periodic_summary"
+				if err := enc.Encode(summary); err != nil {
+					return exitCode(targets, *failOnAnyDownFlag, *quorumFlag)
+				}
+			} else {
+				disp.summary(periodicSummary(targets, now, *latencyUnitFlag))
+			}
+			if *summaryResetFlag {
+				for _, t := range targets {
+					t.reset(now)
+				}
+			}
 
-	// Get current time for status display
-	timeNow := time.Now().Format("15:04:05")
+		case <-winchChan:
+			if !jsonOutput {
+				disp.resize(targets)
+			}
 
-	// Print connection status with color
-	if connected {
-		success.Printf("[%s] ✓ CONNECTED    ", timeNow)
-	} else {
-		failure.Printf("[%s] ✗ DISCONNECTED ", timeNow)
+		case <-deadline:
+			finish()
+			return exitCode(targets, *failOnAnyDownFlag, *quorumFlag)
+		}
 	}
+}
 
-	// Print duration of current state if available
-	if duration > 0 {
-		info.Printf("Duration: %s", formatDuration(duration))
+// exitCode returns 1 if the connection was down as of the last checks, or
+// with failOnAnyDown if any target was down or had an outage at all, and 0
+// otherwise
+func exitCode(targets []*target, failOnAnyDown bool, quorum int) int {
+	if failOnAnyDown {
+		for _, t := range targets {
+			checked := !t.statusChangeTime.IsZero()
+			if checked && !t.lastStatus || len(t.incidents) > 0 {
+				return 1
+			}
+		}
+		return 0
+	}
+	if !connectionUp(targets, quorum, time.Now()) {
+		return 1
 	}
+	return 0
+}
 
-	// If connected, print network latency
-	if connected {
-		// Move cursor to row 6, clear line
-		fmt.Print("\033[6;0H\033[K")
-		fmt.Print("Network Latency: ")
+// periodicSummary formats a short rollup of every target for
+// -summary-interval
+func periodicSummary(targets []*target, now time.Time, latencyUnit string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] Summary\n", clock(now))
+	for _, t := range targets {
+		fmt.Fprintf(&b, "  %s:", t.url)
+		if pct, ok := t.uptimePercent(); ok {
+			fmt.Fprintf(&b, " %.1f%% up,", pct)
+		}
+		fmt.Fprintf(&b, " %d outages", len(t.incidents))
+		if t.latencyCount > 0 {
+			fmt.Fprintf(&b, ", avg %s, p95 %s", formatLatency(t.avgLatency(), latencyUnit), formatLatency(percentile(t.latencySamples, 95), latencyUnit))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
 
-		// Print measured latency
-		fmt.Printf("%s", latency.Round(time.Millisecond))
+// printSummary prints the uptime, downtime and latency aggregates of every
+// target, as a single JSON object when enc is set, followed by latency
+// histograms with the given bucket bounds unless they are nil
+func printSummary(targets []*target, enc *json.Encoder, latencyUnit string, histogramBounds []time.Duration) {
+	if enc != nil {
+		enc.Encode(newSummaryRecord(targets))
+		return
+	}
+	fmt.Println("\n\nExiting Connection Monitor")
+	writeStats(os.Stdout, targets, latencyUnit)
+	if histogramBounds == nil {
+		return
+	}
+	for _, t := range targets {
+		if len(t.latencySamples) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s latency distribution\n", t.url)
+		writeHistogram(os.Stdout, t.latencySamples, histogramBounds, latencyUnit)
 	}
 }
 
-// formatDuration returns a human-readable string for a time.Duration (e.g., 1h 2m 3s)
-func formatDuration(d time.Duration) string {
-	d = d.Round(time.Second)
-	h := d / time.Hour
-	d -= h * time.Hour
-	m := d / time.Minute
-	d -= m * time.Minute
-	s := d / time.Second
-
-	if h > 0 {
-		return fmt.Sprintf("%dh %dm %ds", h, m, s)
-	} else if m > 0 {
-		return fmt.Sprintf("%dm %ds", m, s)
-	}
-	return fmt.Sprintf("%ds", s)
+// writeStats writes the uptime, downtime and latency aggregates of every
+// target to w, with latencies in latencyUnit
+func writeStats(w io.Writer, targets []*target, latencyUnit string) {
+	for _, t := range targets {
+		uptime, degraded, downtime := t.totals()
+		fmt.Fprintf(w, "\n%s\n", t.url)
+		fmt.Fprintf(w, "Total uptime: %s\n", formatDuration(uptime))
+		if t.latencyWarn > 0 {
+			fmt.Fprintf(w, "Total degraded: %s\n", formatDuration(degraded))
+		}
+		fmt.Fprintf(w, "Total downtime: %s\n", formatDuration(downtime))
+		if rate, ok := t.recent.SuccessRate(); ok {
+			fmt.Fprintf(w, "Last %d checks: %.1f%% up\n", t.recent.Len(), rate)
+		}
+		if t.latencyCount > 0 {
+			fmt.Fprintf(w, "Min latency: %s\n", formatLatency(t.minLatency, latencyUnit))
+			fmt.Fprintf(w, "Max latency: %s\n", formatLatency(t.maxLatency, latencyUnit))
+			fmt.Fprintf(w, "Avg latency: %s\n", formatLatency(t.avgLatency(), latencyUnit))
+			fmt.Fprintf(w, "Jitter: %s\n", formatLatency(t.jitter(), latencyUnit))
+			fmt.Fprintf(w, "P50 latency: %s\n", formatLatency(percentile(t.latencySamples, 50), latencyUnit))
+			fmt.Fprintf(w, "P95 latency: %s\n", formatLatency(percentile(t.latencySamples, 95), latencyUnit))
+			fmt.Fprintf(w, "P99 latency: %s\n", formatLatency(percentile(t.latencySamples, 99), latencyUnit))
+		}
+		if s := t.throughput; s.count > 0 {
+			fmt.Fprintf(w, "Throughput: min %.1f Mbps, max %.1f Mbps, avg %.1f Mbps\n", s.min, s.max, s.avg())
+		}
+		if len(t.failures) > 0 {
+			fmt.Fprintf(w, "Failures: %s\n", t.failures)
+		}
+		if t.probesSent > 0 {
+			fmt.Fprintf(w, "Packet loss: %.1f%% (%d of %d probes)\n", lossPercent(t.probesLost, t.probesSent), t.probesLost, t.probesSent)
+		}
+		if len(t.incidents) > 0 {
+			longest, average := t.outageStats()
+			fmt.Fprintf(w, "Outages: %d (longest %s, average %s)\n", len(t.incidents), formatDuration(longest), formatDuration(average))
+			for _, i := range t.incidents {
+				end := "ongoing"
+				if !i.ongoing() {
+					end = clock(i.End)
+				}
+				fmt.Fprintf(w, "  %s - %s (%s)\n", clock(i.Start), end, formatDuration(i.duration(t.lastCheckTime)))
+			}
+		}
+	}
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics exposes per-target check results to Prometheus on /metrics
+type metrics struct {
+	up       *prometheus.GaugeVec
+	latency  *prometheus.HistogramVec
+	checks   *prometheus.CounterVec
+	failures *prometheus.CounterVec
+	server   *http.Server
+}
+
+// startMetrics registers the metrics and starts serving them on addr
+func startMetrics(addr string) (*metrics, error) {
+	m := &metrics{
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "networkcheck_up",
+			Help: "Whether the target is currently connected (1) or not (0).",
+		}, []string{"url"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "networkcheck_latency_seconds",
+			Help:    "Latency of successful checks.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"url"}),
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "networkcheck_checks_total",
+			Help: "Total number of checks performed.",
+		}, []string{"url"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "networkcheck_failures_total",
+			Help: "Total number of failed checks.",
+		}, []string{"url"}),
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m.up, m.latency, m.checks, m.failures)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	var err error
+	if m.server, err = serveHTTP("metrics", addr, mux); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// observe updates the metrics with a target's latest check
+func (m *metrics) observe(t *target) {
+	up := 0.0
+	if t.lastStatus {
+		up = 1
+	}
+	m.up.WithLabelValues(t.url).Set(up)
+	m.checks.WithLabelValues(t.url).Inc()
+	if t.last.Connected {
+		m.latency.WithLabelValues(t.url).Observe(t.last.Latency.Seconds())
+	} else {
+		m.failures.WithLabelValues(t.url).Inc()
+	}
+}
+
+func (m *metrics) Shutdown() {
+	shutdownHTTP(m.server)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttDisconnectWait is how long Close lets queued messages go out before
+// dropping the broker connection
+const mqttDisconnectWait = 250 * time.Millisecond
+
+// mqttConfig holds the -mqtt-* options
+type mqttConfig struct {
+	broker, topic      string
+	username, password string
+	qos                byte
+	onCheck            bool // publish every check rather than state changes
+	plain              bool // "<url> <status>" payloads rather than JSON
+}
+
+// mqttPublisher publishes checks or state changes to an MQTT topic, and
+// "online" or "offline" to <topic>/availability, which the broker sets to
+// "offline" itself if the monitor dies. The client reconnects on its own
+// when the broker connection drops.
+type mqttPublisher struct {
+	cfg    mqttConfig
+	client mqtt.Client
+}
+
+// newMQTTPublisher starts connecting to the broker in the background, so a
+// broker that is down at startup doesn't stop the monitor
+func newMQTTPublisher(cfg mqttConfig) *mqttPublisher {
+	availability := cfg.topic + "/availability"
+	host, _ := os.Hostname()
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.broker).
+		SetClientID(fmt.Sprintf("networkcheck-%s-%d", host, os.Getpid())).
+		SetUsername(cfg.username).
+		SetPassword(cfg.password).
+		SetWill(availability, "offline", cfg.qos, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			slog.Info("connected to MQTT broker", "broker", cfg.broker)
+			c.Publish(availability, cfg.qos, true, "online")
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			slog.Warn("lost MQTT broker connection, reconnecting", "broker", cfg.broker, "error", err)
+		})
+	p := &mqttPublisher{cfg: cfg, client: mqtt.NewClient(opts)}
+	p.client.Connect()
+	return p
+}
+
+// Write publishes a check with -mqtt-publish check
+func (p *mqttPublisher) Write(url string, r checkResult) error {
+	if !p.cfg.onCheck {
+		return nil
+	}
+	if p.cfg.plain {
+		status := "down"
+		if r.Connected {
+			status = "up " + formatLatency(r.Latency, "auto")
+		}
+		p.publish(url + " " + status)
+		return nil
+	}
+	return p.publishJSON(newCheckRecord(url, r))
+}
+
+// Notify publishes a state change with -mqtt-publish change
+func (p *mqttPublisher) Notify(c stateChange) {
+	if p.cfg.onCheck {
+		return
+	}
+	if p.cfg.plain {
+		p.publish(c.URL + " " + c.To.String())
+		return
+	}
+	if err := p.publishJSON(newWebhookPayload(c)); err != nil {
+		slog.Error("cannot encode MQTT payload", "error", err)
+	}
+}
+
+func (p *mqttPublisher) publishJSON(v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.publish(string(payload))
+	return nil
+}
+
+// publish sends payload without waiting for the broker, logging a failure
+// once it is known
+func (p *mqttPublisher) publish(payload string) {
+	token := p.client.Publish(p.cfg.topic, p.cfg.qos, false, payload)
+	go func() {
+		if token.WaitTimeout(webhookTimeout) && token.Error() != nil {
+			slog.Error("MQTT publish failed", "topic", p.cfg.topic, "error", token.Error())
+		}
+	}()
+}
+
+// Close marks the monitor offline, which the will only does when it dies,
+// and disconnects
+func (p *mqttPublisher) Close() error {
+	if p.client.IsConnected() {
+		p.client.Publish(p.cfg.topic+"/availability", p.cfg.qos, true, "offline").WaitTimeout(webhookTimeout)
+	}
+	p.client.Disconnect(uint(mqttDisconnectWait / time.Millisecond))
+	return nil
+}
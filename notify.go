@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// notifier delivers alerts about targets changing state. Notify is called
+// from the main loop and must not block it.
+type notifier interface {
+	Notify(c stateChange)
+}
+
+// defaultNotifyOn is the events alerted unless -notify-on picks others:
+// connections going down and coming back up
+const defaultNotifyOn = "down,up"
+
+// notifyEventNames are the events -notify-on can select
+var notifyEventNames = []string{"down", "up", "degraded", "flap", "spike", "sustained"}
+
+// notifyEvents is the set of events passed on to the notifiers
+type notifyEvents map[string]bool
+
+// parseNotifyEvents parses a comma-separated list of notifyEventNames
+func parseNotifyEvents(s string) (notifyEvents, error) {
+	events := notifyEvents{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(notifyEventNames, field) {
+			return nil, fmt.Errorf("unknown event %q, want some of %s", field, strings.Join(notifyEventNames, ", "))
+		}
+		events[field] = true
+	}
+	return events, nil
+}
+
+// allows reports whether event is passed on to the notifiers
+func (e notifyEvents) allows(event string) bool {
+	return e[event]
+}
+
+// resolveNotifier is implemented by notifiers that open incidents, which
+// must be resolved when the target recovers even if -notify-on leaves out
+// recoveries, or they would stay open for good
+type resolveNotifier interface {
+	Resolve(c stateChange)
+}
+
+// dispatcher passes alerts on to every notifier. It is the one place
+// -notify-on is applied, the same way for every notifier.
+type dispatcher struct {
+	notifiers []notifier
+	on        notifyEvents
+}
+
+func (d *dispatcher) add(n notifier) {
+	d.notifiers = append(d.notifiers, n)
+}
+
+// change passes on a state change. A recovery -notify-on leaves out only
+// resolves the incidents opened for the outage.
+func (d *dispatcher) change(c stateChange) {
+	if !d.on.allows(c.To.String()) {
+		if c.recovered() {
+			for _, n := range d.notifiers {
+				if n, ok := n.(resolveNotifier); ok {
+					n.Resolve(c)
+				}
+			}
+		}
+		return
+	}
+	for _, n := range d.notifiers {
+		n.Notify(c)
+	}
+}
+
+func (d *dispatcher) spike(s latencySpike) {
+	if !d.on.allows("spike") {
+		return
+	}
+	for _, n := range d.notifiers {
+		if n, ok := n.(spikeNotifier); ok {
+			n.NotifySpike(s)
+		}
+	}
+}
+
+func (d *dispatcher) sustained(s sustainedLatency) {
+	if !d.on.allows("sustained") {
+		return
+	}
+	for _, n := range d.notifiers {
+		if n, ok := n.(sustainedNotifier); ok {
+			n.NotifySustained(s)
+		}
+	}
+}
+
+func (d *dispatcher) flap(f flapAlert) {
+	if !d.on.allows("flap") {
+		return
+	}
+	for _, n := range d.notifiers {
+		if n, ok := n.(flapNotifier); ok {
+			n.NotifyFlap(f)
+		}
+	}
+}
+
+// desktopNotifier pops up an OS desktop notification on state changes
+type desktopNotifier struct {
+	// cooldown suppresses notifications that follow the last one too
+	// closely, e.g. while the connection is flapping
+	cooldown time.Duration
+	lastSent time.Time
+}
+
+func newDesktopNotifier(cooldown time.Duration) *desktopNotifier {
+	return &desktopNotifier{cooldown: cooldown}
+}
+
+func (n *desktopNotifier) Notify(c stateChange) {
+	if !n.lastSent.IsZero() && c.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = c.Time
+
+	title := "Connection lost"
+	switch c.To {
+	case stateUp:
+		title = "Connection restored"
+	case stateDegraded:
+		title = "Connection degraded"
+	}
+	message := fmt.Sprintf("%s at %s", c.URL, clock(c.Time))
+	if c.recovered() {
+		message = fmt.Sprintf("%s recovered after %s at %s", c.URL, formatDuration(c.Previous), clock(c.Time))
+	}
+	desktopAlert(title, message)
+}
+
+func (n *desktopNotifier) NotifySpike(s latencySpike) {
+	if !n.lastSent.IsZero() && s.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = s.Time
+	desktopAlert("Latency spike", s.String())
+}
+
+func (n *desktopNotifier) NotifySustained(s sustainedLatency) {
+	if !n.lastSent.IsZero() && s.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = s.Time
+	desktopAlert("Sustained high latency", s.String())
+}
+
+func (n *desktopNotifier) NotifyFlap(f flapAlert) {
+	if !n.lastSent.IsZero() && f.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = f.Time
+	desktopAlert("Connection flapping", f.String())
+}
+
+// bellNotifier rings the terminal bell when a target goes down, and
+// optionally when it recovers
+type bellNotifier struct {
+	w          io.Writer
+	onRecovery bool
+
+	// cooldown suppresses bells that follow the last one too closely
+	cooldown time.Duration
+	lastSent time.Time
+}
+
+func (n *bellNotifier) Notify(c stateChange) {
+	if c.To != stateDown && !(n.onRecovery && c.recovered()) {
+		return
+	}
+	if !n.lastSent.IsZero() && c.Time.Sub(n.lastSent) < n.cooldown {
+		return
+	}
+	n.lastSent = c.Time
+	fmt.Fprint(n.w, "\a")
+}
+
+// expandTemplate fills a message template with the details of a state
+// change. Supported placeholders are {status} (up/down/degraded), {url},
+// {time} and {duration}, the length of the state just left.
+func expandTemplate(tmpl string, c stateChange) string {
+	return strings.NewReplacer(
+		"{status}", c.To.String(),
+		"{url}", c.URL,
+		"{time}", clock(c.Time),
+		"{duration}", formatDuration(c.Previous),
+	).Replace(tmpl)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseNotifyEvents(t *testing.T) {
+	events, err := parseNotifyEvents(" down, spike ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || !events["down"] || !events["spike"] {
+		t.Errorf("events = %v, want down and spike", events)
+	}
+	for _, in := range []string{"", "down,", "down,recovered"} {
+		if _, err := parseNotifyEvents(in); err == nil {
+			t.Errorf("parseNotifyEvents(%q) succeeded", in)
+		}
+	}
+}
+
+// recordingNotifier records every alert it is passed
+type recordingNotifier struct {
+	events   []string
+	resolved []string
+}
+
+func (n *recordingNotifier) Notify(c stateChange)     { n.events = append(n.events, c.To.String()) }
+func (n *recordingNotifier) NotifySpike(latencySpike) { n.events = append(n.events, "spike") }
+func (n *recordingNotifier) NotifySustained(sustainedLatency) {
+	n.events = append(n.events, "sustained")
+}
+func (n *recordingNotifier) NotifyFlap(flapAlert)  { n.events = append(n.events, "flap") }
+func (n *recordingNotifier) Resolve(c stateChange) { n.resolved = append(n.resolved, c.URL) }
+
+func TestDispatcher(t *testing.T) {
+	// As with -silent-success
+	events, err := parseNotifyEvents(defaultNotifyOn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(events, "up")
+
+	n := &recordingNotifier{}
+	alerts := &dispatcher{on: events}
+	alerts.add(n)
+	alerts.change(stateChange{URL: "https://example.com", From: stateUp, To: stateDown})
+	alerts.spike(latencySpike{})
+	alerts.sustained(sustainedLatency{})
+	alerts.flap(flapAlert{})
+	alerts.change(stateChange{URL: "https://example.com", From: stateDown, To: stateUp})
+
+	if !slices.Equal(n.events, []string{"down"}) {
+		t.Errorf("passed on %q, want only down", n.events)
+	}
+	// Incidents must still close, or they'd stay open for good
+	if !slices.Equal(n.resolved, []string{"https://example.com"}) {
+		t.Errorf("resolved %q, want the recovered target", n.resolved)
+	}
+}
@@ -0,0 +1,149 @@
+//go:build otel
+
+package main
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// otlpTimeout bounds each export, and the final flush on exit
+	otlpTimeout = 5 * time.Second
+	// otlpInterval is how often metrics are pushed to the collector
+	otlpInterval = 10 * time.Second
+)
+
+// otlpExporter pushes per-target check results to an OpenTelemetry
+// collector over OTLP/HTTP, and a span per check when tracing
+type otlpExporter struct {
+	meters   *sdkmetric.MeterProvider
+	tracers  *sdktrace.TracerProvider // nil unless -otlp-traces is set
+	tracer   trace.Tracer
+	up       metric.Int64Gauge
+	latency  metric.Float64Histogram
+	checks   metric.Int64Counter
+	failures metric.Int64Counter
+}
+
+// startOTLP sets up the exporters for the collector at endpoint, an
+// http:// or https:// URL without a path
+func startOTLP(endpoint string, spans bool) (*otlpExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	res := resource.NewSchemaless(semconv.ServiceName("networkcheck"))
+
+	metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(u.Host), otlpmetrichttp.WithTimeout(otlpTimeout)}
+	if u.Scheme == "http" {
+		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+	}
+	metricExporter, err := otlpmetrichttp.New(context.Background(), metricOpts...)
+	if err != nil {
+		return nil, err
+	}
+	e := &otlpExporter{
+		meters: sdkmetric.NewMeterProvider(
+			sdkmetric.WithResource(res),
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(otlpInterval), sdkmetric.WithTimeout(otlpTimeout))),
+		),
+	}
+	meter := e.meters.Meter("networkcheck")
+	if e.up, err = meter.Int64Gauge("networkcheck.up", metric.WithDescription("Whether the target is currently connected (1) or not (0).")); err != nil {
+		return nil, err
+	}
+	if e.latency, err = meter.Float64Histogram("networkcheck.latency", metric.WithUnit("s"), metric.WithDescription("Latency of successful checks.")); err != nil {
+		return nil, err
+	}
+	if e.checks, err = meter.Int64Counter("networkcheck.checks", metric.WithDescription("Total number of checks performed.")); err != nil {
+		return nil, err
+	}
+	if e.failures, err = meter.Int64Counter("networkcheck.failures", metric.WithDescription("Total number of failed checks.")); err != nil {
+		return nil, err
+	}
+
+	if spans {
+		traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host), otlptracehttp.WithTimeout(otlpTimeout)}
+		if u.Scheme == "http" {
+			traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		}
+		traceExporter, err := otlptracehttp.New(context.Background(), traceOpts...)
+		if err != nil {
+			return nil, err
+		}
+		e.tracers = sdktrace.NewTracerProvider(sdktrace.WithResource(res), sdktrace.WithBatcher(traceExporter, sdktrace.WithExportTimeout(otlpTimeout)))
+		e.tracer = e.tracers.Tracer("networkcheck")
+	}
+	return e, nil
+}
+
+// observe records a target's latest check
+func (e *otlpExporter) observe(t *target) {
+	ctx := context.Background()
+	attrs := metric.WithAttributes(attribute.String("url", t.url))
+	up := int64(0)
+	if t.lastStatus {
+		up = 1
+	}
+	e.up.Record(ctx, up, attrs)
+	e.checks.Add(ctx, 1, attrs)
+	if t.last.Connected {
+		e.latency.Record(ctx, t.last.Latency.Seconds(), attrs)
+	} else {
+		e.failures.Add(ctx, 1, attrs)
+	}
+	if e.tracer != nil {
+		e.span(t.url, t.last)
+	}
+}
+
+// span records a check as a span, with its -trace phases as events
+// timed from the start of the check
+func (e *otlpExporter) span(target string, r checkResult) {
+	attrs := []attribute.KeyValue{attribute.String("url", target), attribute.Bool("connected", r.Connected)}
+	if r.StatusCode != 0 {
+		attrs = append(attrs, attribute.Int("http.response.status_code", r.StatusCode))
+	}
+	if !r.Connected {
+		attrs = append(attrs, attribute.String("error.type", failureCategory(r)))
+	}
+	_, s := e.tracer.Start(context.Background(), "check", trace.WithTimestamp(r.Time), trace.WithAttributes(attrs...))
+	if p := r.Phases; p != nil {
+		at := r.Time
+		for _, phase := range []struct {
+			name string
+			d    time.Duration
+		}{{"dns", p.DNS}, {"connect", p.Connect}, {"tls", p.TLS}} {
+			if phase.d > 0 {
+				at = at.Add(phase.d)
+				s.AddEvent(phase.name+" done", trace.WithTimestamp(at))
+			}
+		}
+		if p.TTFB > 0 {
+			s.AddEvent("first byte", trace.WithTimestamp(r.Time.Add(p.TTFB)))
+		}
+	}
+	s.End(trace.WithTimestamp(r.Time.Add(r.Latency)))
+}
+
+// Shutdown flushes anything not yet exported
+func (e *otlpExporter) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+	e.meters.Shutdown(ctx)
+	if e.tracers != nil {
+		e.tracers.Shutdown(ctx)
+	}
+}
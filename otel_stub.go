@@ -0,0 +1,17 @@
+//go:build !otel
+
+package main
+
+import "errors"
+
+// otlpExporter needs the OpenTelemetry SDK, which is only built in with
+// -tags otel
+type otlpExporter struct{}
+
+func startOTLP(string, bool) (*otlpExporter, error) {
+	return nil, errors.New("networkcheck was built without OpenTelemetry support, rebuild with -tags otel")
+}
+
+func (*otlpExporter) observe(*target) {}
+
+func (*otlpExporter) Shutdown() {}
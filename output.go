@@ -0,0 +1,220 @@
+package main
+
+import "time"
+
+// checkLog is a file that every check result is appended to
+type checkLog interface {
+	Write(url string, r checkResult) error
+	Close() error
+}
+
+// checkRecord is the JSON line emitted for every check in json format
+type checkRecord struct {
+	Timestamp  string  `json:"timestamp"`
+	URL        string  `json:"url"`
+	Connected  bool    `json:"connected"`
+	LatencyMs  float64 `json:"latency_ms"`
+	StatusCode int     `json:"status_code"`
+	Error      string  `json:"error,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+	RemoteIP   string  `json:"remote_ip,omitempty"`
+	FinalURL   string  `json:"final_url,omitempty"`
+	Fallback   string  `json:"fallback_url,omitempty"`
+
+	GatewayConnected *bool   `json:"gateway_connected,omitempty"`
+	Diagnosis        string  `json:"diagnosis,omitempty"`
+	Bytes            int64   `json:"bytes,omitempty"`
+	Mbps             float64 `json:"throughput_mbps,omitempty"`
+
+	Phases     *phaseRecord `json:"phases,omitempty"`
+	CertExpiry string       `json:"cert_expiry,omitempty"`
+
+	// ResponseBytes is nil when the size is unknown
+	ResponseBytes *int64 `json:"response_bytes,omitempty"`
+	Server        string `json:"server,omitempty"`
+
+	Burst *burstRecord `json:"burst,omitempty"`
+}
+
+// burstRecord is the JSON representation of a -burst check
+type burstRecord struct {
+	Sent         int     `json:"sent"`
+	Lost         int     `json:"lost"`
+	LossPct      float64 `json:"loss_pct"`
+	MinLatencyMs float64 `json:"min_latency_ms"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+// phaseRecord is the JSON representation of a traced latency breakdown
+type phaseRecord struct {
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	TLSMs     float64 `json:"tls_ms"`
+	TTFBMs    float64 `json:"ttfb_ms"`
+	Reused    bool    `json:"reused"`
+}
+
+// targetSummary holds the aggregates for one target in the exit summary
+type targetSummary struct {
+	URL          string  `json:"url"`
+	UptimeMs     float64 `json:"uptime_ms"`
+	DegradedMs   float64 `json:"degraded_ms"`
+	DowntimeMs   float64 `json:"downtime_ms"`
+	Checks       int     `json:"successful_checks"`
+	RecentChecks int     `json:"recent_checks"`
+	RecentUpPct  float64 `json:"recent_up_pct"`
+	MinLatencyMs float64 `json:"min_latency_ms,omitempty"`
+	MaxLatencyMs float64 `json:"max_latency_ms,omitempty"`
+	AvgLatencyMs float64 `json:"avg_latency_ms,omitempty"`
+	JitterMs     float64 `json:"jitter_ms,omitempty"`
+	P50LatencyMs float64 `json:"p50_latency_ms,omitempty"`
+	P95LatencyMs float64 `json:"p95_latency_ms,omitempty"`
+	P99LatencyMs float64 `json:"p99_latency_ms,omitempty"`
+	MinMbps      float64 `json:"min_throughput_mbps,omitempty"`
+	MaxMbps      float64 `json:"max_throughput_mbps,omitempty"`
+	AvgMbps      float64 `json:"avg_throughput_mbps,omitempty"`
+
+	Failures map[string]int `json:"failures,omitempty"`
+
+	// PacketLossPct is over every -burst probe, nil without -burst
+	PacketLossPct *float64 `json:"packet_loss_pct,omitempty"`
+
+	Outages         []outageRecord `json:"outages"`
+	LongestOutageMs float64        `json:"longest_outage_ms"`
+	AverageOutageMs float64        `json:"average_outage_ms"`
+}
+
+// outageRecord is the JSON representation of one incident
+type outageRecord struct {
+	Start      string  `json:"start"`
+	End        string  `json:"end,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Ongoing    bool    `json:"ongoing"`
+}
+
+// summaryRecord is the final JSON object emitted at exit in json format
+type summaryRecord struct {
+	Type    string          `json:"type"`
+	Targets []targetSummary `json:"targets"`
+}
+
+// newCheckRecord converts a check result for url into its JSON representation
+func newCheckRecord(url string, r checkResult) checkRecord {
+	rec := checkRecord{
+		Timestamp:  stamp(r.Time),
+		URL:        url,
+		Connected:  r.Connected,
+		LatencyMs:  durationMs(r.Latency),
+		StatusCode: r.StatusCode,
+		Reason:     r.Reason,
+		FinalURL:   r.FinalURL,
+		Fallback:   r.FallbackURL,
+		Bytes:      r.Bytes,
+		Mbps:       r.Mbps,
+		Server:     r.Server,
+	}
+	if r.StatusCode != 0 && r.ResponseSize >= 0 {
+		rec.ResponseBytes = &r.ResponseSize
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	if r.Gateway != nil {
+		rec.GatewayConnected = &r.Gateway.Connected
+		rec.Diagnosis = r.diagnosis()
+	}
+	if r.RemoteIP != nil {
+		rec.RemoteIP = r.RemoteIP.String()
+	}
+	if !r.CertExpiry.IsZero() {
+		rec.CertExpiry = stamp(r.CertExpiry)
+	}
+	if b := r.Burst; b != nil {
+		rec.Burst = &burstRecord{
+			Sent:         b.Sent,
+			Lost:         b.Lost,
+			LossPct:      b.loss(),
+			MinLatencyMs: durationMs(b.Min),
+			AvgLatencyMs: durationMs(b.Avg),
+			MaxLatencyMs: durationMs(b.Max),
+		}
+	}
+	if p := r.Phases; p != nil {
+		rec.Phases = &phaseRecord{
+			DNSMs:     durationMs(p.DNS),
+			ConnectMs: durationMs(p.Connect),
+			TLSMs:     durationMs(p.TLS),
+			TTFBMs:    durationMs(p.TTFB),
+			Reused:    p.Reused,
+		}
+	}
+	return rec
+}
+
+// newSummaryRecord builds the exit summary for all targets
+func newSummaryRecord(targets []*target) summaryRecord {
+	summary := summaryRecord{Type: "summary"}
+	for _, t := range targets {
+		summary.Targets = append(summary.Targets, newTargetSummary(t))
+	}
+	return summary
+}
+
+// newTargetSummary builds the aggregates for a single target
+func newTargetSummary(t *target) targetSummary {
+	uptime, degraded, downtime := t.totals()
+	ts := targetSummary{
+		URL:        t.url,
+		UptimeMs:   durationMs(uptime),
+		DegradedMs: durationMs(degraded),
+		DowntimeMs: durationMs(downtime),
+		Checks:     t.latencyCount,
+	}
+	if rate, ok := t.recent.SuccessRate(); ok {
+		ts.RecentChecks = t.recent.Len()
+		ts.RecentUpPct = rate
+	}
+	if t.latencyCount > 0 {
+		ts.MinLatencyMs = durationMs(t.minLatency)
+		ts.MaxLatencyMs = durationMs(t.maxLatency)
+		ts.AvgLatencyMs = durationMs(t.avgLatency())
+		ts.JitterMs = durationMs(t.jitter())
+		ts.P50LatencyMs = durationMs(percentile(t.latencySamples, 50))
+		ts.P95LatencyMs = durationMs(percentile(t.latencySamples, 95))
+		ts.P99LatencyMs = durationMs(percentile(t.latencySamples, 99))
+	}
+	if t.throughput.count > 0 {
+		ts.MinMbps = t.throughput.min
+		ts.MaxMbps = t.throughput.max
+		ts.AvgMbps = t.throughput.avg()
+	}
+	if len(t.failures) > 0 {
+		ts.Failures = t.failures
+	}
+	if t.probesSent > 0 {
+		loss := lossPercent(t.probesLost, t.probesSent)
+		ts.PacketLossPct = &loss
+	}
+	longest, average := t.outageStats()
+	ts.LongestOutageMs = durationMs(longest)
+	ts.AverageOutageMs = durationMs(average)
+	ts.Outages = []outageRecord{}
+	for _, i := range t.incidents {
+		rec := outageRecord{
+			Start:      stamp(i.Start),
+			DurationMs: durationMs(i.duration(t.lastCheckTime)),
+			Ongoing:    i.ongoing(),
+		}
+		if !i.ongoing() {
+			rec.End = stamp(i.End)
+		}
+		ts.Outages = append(ts.Outages, rec)
+	}
+	return ts
+}
+
+// durationMs returns d as fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutyNotifier opens a PagerDuty incident when a target goes down and
+// resolves it when the target recovers, whether or not -notify-on passes
+// on recoveries
+type pagerDutyNotifier struct {
+	routingKey string
+	poster     *poster
+
+	// dedupKeys holds the key of each target's open incident, so the
+	// resolve event matches the trigger
+	dedupKeys map[string]string
+}
+
+func newPagerDutyNotifier(routingKey string, poster *poster) *pagerDutyNotifier {
+	return &pagerDutyNotifier{routingKey: routingKey, poster: poster, dedupKeys: make(map[string]string)}
+}
+
+// pagerDutyEvent is the body of an Events API v2 request
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // "trigger" or "resolve"
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary   string `json:"summary"`
+	Source    string `json:"source"`
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (n *pagerDutyNotifier) Notify(c stateChange) {
+	switch {
+	case c.To == stateDown:
+		key := fmt.Sprintf("networkcheck:%s:%d", c.URL, c.Time.Unix())
+		n.dedupKeys[c.URL] = key
+		n.poster.post("pagerduty", pagerDutyEventsURL, pagerDutyEvent{
+			RoutingKey:  n.routingKey,
+			EventAction: "trigger",
+			DedupKey:    key,
+			Payload: &pagerDutyPayload{
+				Summary:   fmt.Sprintf("%s is down (was %s for %s)", c.URL, c.From, formatDuration(c.Previous)),
+				Source:    c.URL,
+				Severity:  "critical",
+				Timestamp: stamp(c.Time),
+			},
+		})
+	case c.recovered():
+		n.Resolve(c)
+	}
+}
+
+// Resolve resolves the incident open for the target that recovered, if any
+func (n *pagerDutyNotifier) Resolve(c stateChange) {
+	key, ok := n.dedupKeys[c.URL]
+	if !ok {
+		return
+	}
+	delete(n.dedupKeys, c.URL)
+	n.poster.post("pagerduty", pagerDutyEventsURL, pagerDutyEvent{
+		RoutingKey:  n.routingKey,
+		EventAction: "resolve",
+		DedupKey:    key,
+	})
+}
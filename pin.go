@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pinnedHosts holds the address each target host resolved to when it was
+// first seen, for -pin-ip to dial instead of resolving on every check
+type pinnedHosts struct {
+	ipVersion string
+	timeout   time.Duration
+
+	mu  sync.RWMutex
+	ips map[string]net.IP
+}
+
+func newPinnedHosts(ipVersion string, timeout time.Duration) *pinnedHosts {
+	return &pinnedHosts{ipVersion: ipVersion, timeout: timeout, ips: make(map[string]net.IP)}
+}
+
+// pin resolves host unless it is already pinned or is an IP address
+func (p *pinnedHosts) pin(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	p.mu.RLock()
+	_, ok := p.ips[host]
+	p.mu.RUnlock()
+	if ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, familyNetwork("ip", p.ipVersion), host)
+	if err != nil {
+		return fmt.Errorf("cannot pin %s: %w", host, err)
+	}
+	p.mu.Lock()
+	p.ips[host] = ips[0]
+	p.mu.Unlock()
+	return nil
+}
+
+// addr replaces the host of a host:port address with its pinned IP
+func (p *pinnedHosts) addr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	p.mu.RLock()
+	ip, ok := p.ips[host]
+	p.mu.RUnlock()
+	if !ok {
+		return addr
+	}
+	return net.JoinHostPort(ip.String(), port)
+}
+
+// String lists the pinned hosts, e.g. "www.google.com → 142.250.74.36"
+func (p *pinnedHosts) String() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var pins []string
+	for host, ip := range p.ips {
+		pins = append(pins, host+" → "+ip.String())
+	}
+	slices.Sort(pins)
+	return strings.Join(pins, ", ")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPinnedHostsPin(t *testing.T) {
+	p := newPinnedHosts("4", time.Second)
+	if err := p.pin("127.0.0.1"); err != nil || len(p.ips) != 0 {
+		t.Errorf("pin of an IP address = %v with %d pins, want nothing pinned", err, len(p.ips))
+	}
+	if err := p.pin("localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.addr("localhost:8080"); got != "127.0.0.1:8080" {
+		t.Errorf("addr(localhost:8080) = %s, want 127.0.0.1:8080", got)
+	}
+
+	// A pinned host keeps its address, whatever it resolves to later
+	p.ips["localhost"] = net.ParseIP("127.0.0.2")
+	if err := p.pin("localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.addr("localhost:8080"); got != "127.0.0.2:8080" {
+		t.Errorf("addr after pinning again = %s, want the first pin", got)
+	}
+	if got := p.addr("example.com:443"); got != "example.com:443" {
+		t.Errorf("addr of an unpinned host = %s, want it unchanged", got)
+	}
+	if got := p.addr("no-port"); got != "no-port" {
+		t.Errorf("addr without a port = %s, want it unchanged", got)
+	}
+	if got := p.String(); got != "localhost → 127.0.0.2" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestPinnedChecksDialSameAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The name doesn't resolve, so only the pin can reach the server
+	pins := newPinnedHosts("", time.Second)
+	pins.ips["pinned.invalid"] = net.ParseIP(u.Hostname())
+	transport := newTransport(transportConfig{pins: pins, noKeepAlive: true})
+	transport.Proxy = nil // an HTTP_PROXY would resolve the name itself
+	p := newTestProber(&http.Client{Transport: transport}, "http://pinned.invalid:"+u.Port()+"/")
+
+	for i := range 3 {
+		r := p.Probe(context.Background())
+		if !r.Connected {
+			t.Fatalf("check %d failed: %s %v", i+1, r.Reason, r.Err)
+		}
+		if !r.RemoteIP.Equal(pins.ips["pinned.invalid"]) {
+			t.Errorf("check %d reached %v, want the pinned %v", i+1, r.RemoteIP, pins.ips["pinned.invalid"])
+		}
+	}
+}
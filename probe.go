@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkResult holds the outcome of a single connection check
+type checkResult struct {
+	Time       time.Time
+	Connected  bool
+	Latency    time.Duration
+	StatusCode int    // 0 when no response was received
+	Err        error  // nil unless the request itself failed
+	Reason     string // short failure label for the display, e.g. REFUSED
+
+	// Phases is the latency breakdown of a traced HTTP check, nil otherwise
+	Phases *phaseTimings
+
+	// Bytes and Mbps are the payload size and download speed of a
+	// -throughput check, zero otherwise
+	Bytes int64
+	Mbps  float64
+
+	// FinalURL is where the request ended up after following redirects,
+	// empty when it wasn't redirected
+	FinalURL string
+
+	// FallbackURL is the -fallback-url that succeeded after the primary
+	// check failed, empty when the primary answered
+	FallbackURL string
+
+	// Gateway is the -gateway check taken in the same round, nil when no
+	// gateway is monitored
+	Gateway *checkResult
+
+	// RemoteIP is the server address the check connected to, nil when no
+	// connection was made
+	RemoteIP net.IP
+
+	// LocalIP is the source address the check connected from, nil when no
+	// connection was made
+	LocalIP net.IP
+
+	// CertExpiry is when the server's TLS certificate expires, zero for
+	// plain HTTP
+	CertExpiry time.Time
+
+	// ResponseSize is the size of the HTTP response body, from its
+	// Content-Length or the bytes read when the whole body was read, -1 when
+	// unknown and zero without a response. Server is its Server header.
+	ResponseSize int64
+	Server       string
+
+	// Burst is the packet loss and latency spread of a -burst check, nil
+	// otherwise
+	Burst *burstStats
+}
+
+// Prober performs a single connectivity check against one target, giving up
+// early once ctx is cancelled
+type Prober interface {
+	Probe(ctx context.Context) checkResult
+}
+
+// httpProber checks connectivity with an HTTP request
+type httpProber struct {
+	client           *http.Client
+	timeout          time.Duration
+	method           string
+	url              string
+	header           http.Header
+	isExpectedStatus func(code int) bool
+
+	// trace records the DNS, connect, TLS and first-byte timings
+	trace bool
+
+	// verbose, if set, receives a description of every exchange
+	verbose io.Writer
+
+	// bodyMatches, if set, must accept the start of the response body for
+	// the check to pass, which catches captive portals answering 200
+	bodyMatches func(body []byte) bool
+
+	// maxBodyRead caps how much of the response body is read
+	maxBodyRead int64
+}
+
+// maxBodyCheck is how much of the response body -expect-body gets to see,
+// however low -max-body-read is
+const maxBodyCheck = 64 << 10
+
+// errBodyMismatch is reported when the response body fails -expect-body
+var errBodyMismatch = errors.New("response body does not match the expected content")
+
+func (p *httpProber) Probe(ctx context.Context) checkResult {
+	// The deadline covers reading the body too, on top of the client timeout
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	req, err := p.newRequest(ctx)
+	if err != nil {
+		return checkResult{Time: time.Now(), Err: err}
+	}
+	return p.checkConnection(req)
+}
+
+// newRequest builds the request sent on every check
+func (p *httpProber) newRequest(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, p.method, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range p.header {
+		req.Header[key] = values
+	}
+	return req, nil
+}
+
+// parseHeaders parses "Key: Value" entries into a header set
+func parseHeaders(entries []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not in \"Key: Value\" form", entry)
+		}
+		header.Add(key, strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// checkConnection tests the internet connection and returns connection status, latency,
+// the HTTP status code and any request error. The connection counts as up when
+// isExpectedStatus accepts the response status.
+func (p *httpProber) checkConnection(req *http.Request) (result checkResult) {
+	start := time.Now()
+	result.Time = start
+	var resp *http.Response
+	if p.verbose != nil {
+		// Deferred first so it runs last and sees the complete result, and
+		// written in one go so parallel checks don't interleave
+		defer func() { io.WriteString(p.verbose, verboseReport(req, resp, result)) }()
+	}
+	if p.trace {
+		tracer := newPhaseTracer(start)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), tracer.clientTrace()))
+		defer func() { result.Phases = tracer.result() }()
+	}
+	// GotConn also fires for connections reused from keep-alive
+	var remote, local net.Addr
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			remote, local = info.Conn.RemoteAddr(), info.Conn.LocalAddr()
+		},
+	}))
+	defer func() { result.RemoteIP, result.LocalIP = addrIP(remote), addrIP(local) }()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		result.Err = err
+		result.Reason = failureReason(err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+	result.Server = resp.Header.Get("Server")
+	result.ResponseSize = resp.ContentLength
+	if final := resp.Request.URL.String(); final != req.URL.String() {
+		result.FinalURL = final
+	}
+	result.Connected = p.isExpectedStatus(resp.StatusCode)
+	if !result.Connected {
+		result.Reason = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		result.CertExpiry = resp.TLS.PeerCertificates[0].NotAfter
+	}
+
+	// Read no more than -max-body-read of the body, so a small page leaves
+	// the connection reusable while a huge one doesn't cost its whole
+	// download every check; closing the body discards the rest
+	limit := p.maxBodyRead
+	if p.bodyMatches != nil {
+		limit = max(limit, maxBodyCheck)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if result.ResponseSize < 0 && err == nil && int64(len(body)) < limit {
+		result.ResponseSize = int64(len(body))
+	}
+	if p.bodyMatches != nil {
+		if err != nil {
+			result.Connected = false
+			result.Err = err
+			result.Reason = failureReason(err)
+			return result
+		}
+		if result.Connected && !p.bodyMatches(body) {
+			result.Connected = false
+			result.Err = errBodyMismatch
+			result.Reason = "BODY"
+		}
+	}
+	return result
+}
+
+// checkAll checks every target in parallel so a slow target doesn't delay
+// the others, and returns the results in target order. Checks still running
+// when ctx is cancelled are abandoned and their results are meaningless.
+func checkAll(ctx context.Context, targets []*target) []checkResult {
+	results := make([]checkResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = t.prober.Probe(ctx)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// addrIP returns the IP of a network address, or nil if it has none
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// basicAuth returns the Authorization header value for HTTP basic auth
+func basicAuth(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+// newBodyMatcher builds the -expect-body check from a substring and/or a
+// regular expression, both of which must match when set. It returns nil
+// when neither is set.
+func newBodyMatcher(substring, pattern string) (func(body []byte) bool, error) {
+	if substring == "" && pattern == "" {
+		return nil, nil
+	}
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return func(body []byte) bool {
+		if substring != "" && !bytes.Contains(body, []byte(substring)) {
+			return false
+		}
+		return re == nil || re.Match(body)
+	}, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteIPLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer srv.Close()
+
+	p := newTestProber(&http.Client{Transport: &http.Transport{}}, srv.URL)
+	for i, name := range []string{"fresh connection", "reused connection"} {
+		r := p.Probe(context.Background())
+		if !r.Connected {
+			t.Fatalf("%s: check failed: %v", name, r.Err)
+		}
+		if r.RemoteIP == nil || !r.RemoteIP.IsLoopback() {
+			t.Errorf("%s: RemoteIP = %v, want a loopback address", name, r.RemoteIP)
+		}
+		if i == 0 && r.LocalIP == nil {
+			t.Errorf("%s: LocalIP is nil", name)
+		}
+	}
+}
+
+func TestRemoteIPUnsetWithoutConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(okHandler))
+	srv.Close()
+
+	r := newTestProber(&http.Client{Transport: &http.Transport{}}, srv.URL).Probe(context.Background())
+	if r.Connected {
+		t.Fatal("check of a closed server succeeded")
+	}
+	if r.RemoteIP != nil {
+		t.Errorf("RemoteIP = %v without a connection, want nil", r.RemoteIP)
+	}
+}
+
+func TestMaxBodyReadStopsEarly(t *testing.T) {
+	const total = 256 << 20
+	written := make(chan int, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Chunked with no Content-Length, flushed as it goes, until the
+		// client hangs up
+		chunk := []byte(strings.Repeat("x", 32<<10))
+		n := 0
+		for n < total {
+			m, err := w.Write(chunk)
+			n += m
+			if err != nil {
+				break
+			}
+			w.(http.Flusher).Flush()
+		}
+		written <- n
+	}))
+	defer srv.Close()
+
+	p := newTestProber(&http.Client{Transport: &http.Transport{}}, srv.URL)
+	p.maxBodyRead = 1024
+	r := p.Probe(context.Background())
+	if !r.Connected {
+		t.Fatalf("check failed: %v", r.Err)
+	}
+	if r.ResponseSize != -1 {
+		t.Errorf("ResponseSize = %d for a body beyond the limit with no Content-Length, want -1", r.ResponseSize)
+	}
+	// Socket buffers take a few megabytes whatever the limit
+	if n := <-written; n >= 32<<20 {
+		t.Errorf("server wrote %d bytes before the client hung up, want the download abandoned", n)
+	}
+}
+
+func TestMaxBodyReadResponseSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // chunked, so there is no Content-Length
+		w.Write([]byte(strings.Repeat("x", 3000)))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		limit int64
+		match bool
+		want  int64
+	}{
+		{"body within the limit is measured", 4096, false, 3000},
+		{"body at the limit is unknown", 3000, false, -1},
+		{"body beyond the limit is unknown", 1024, false, -1},
+		{"-expect-body reads past the limit", 1024, true, 3000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProber(&http.Client{}, srv.URL)
+			p.maxBodyRead = tt.limit
+			if tt.match {
+				p.bodyMatches = func(body []byte) bool { return len(body) == 3000 }
+			}
+			r := p.Probe(context.Background())
+			if !r.Connected || r.ResponseSize != tt.want {
+				t.Errorf("got connected %v, ResponseSize %d, want connected, %d (err %v)", r.Connected, r.ResponseSize, tt.want, r.Err)
+			}
+		})
+	}
+}
+
+func TestResponseMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "captive-portal/1.0")
+		w.Header().Set("Content-Length", "11")
+		w.Write([]byte("hello world"))
+	}))
+	defer srv.Close()
+
+	r := newTestProber(&http.Client{}, srv.URL).Probe(context.Background())
+	if !r.Connected {
+		t.Fatalf("check failed: %v", r.Err)
+	}
+	if r.ResponseSize != 11 || r.Server != "captive-portal/1.0" {
+		t.Errorf("ResponseSize %d, Server %q, want 11 and captive-portal/1.0", r.ResponseSize, r.Server)
+	}
+
+	rec := newCheckRecord("https://example.com", r)
+	if rec.ResponseBytes == nil || *rec.ResponseBytes != 11 || rec.Server != "captive-portal/1.0" {
+		t.Errorf("JSON record = %+v, want the response size and server", rec)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "time"
+
+// quorumResult is the overall verdict of a -quorum check across all targets
+type quorumResult struct {
+	up      bool
+	ok      int           // targets currently up
+	total   int           // targets checked
+	latency time.Duration // median latency of the up targets' latest successful checks
+}
+
+// quorumVerdict reports the connection as up when at least n of the
+// targets are, so a single flaky endpoint can't decide the result
+func quorumVerdict(targets []*target, n int) quorumResult {
+	q := quorumResult{total: len(targets)}
+	var latencies []time.Duration
+	now := time.Now()
+	for _, t := range targets {
+		if !t.up(now) {
+			continue
+		}
+		q.ok++
+		// A target still up under -failures-threshold may have just failed,
+		// and a failed check has no latency to count
+		if t.last.Connected {
+			latencies = append(latencies, t.last.Latency)
+		}
+	}
+	q.up = q.ok >= n
+	q.latency = percentile(latencies, 50)
+	return q
+}
+
+// connectionUp is the verdict on the connection as a whole, shared by the
+// exit code and /healthz: -quorum decides when set, otherwise every target
+// checked so far must be up
+func connectionUp(targets []*target, quorum int, now time.Time) bool {
+	if quorum > 0 {
+		return quorumVerdict(targets, quorum).up
+	}
+	for _, t := range targets {
+		if !t.statusChangeTime.IsZero() && !t.up(now) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// quorumTargets returns targets whose latest check succeeded with the given
+// latencies, followed by failed ones
+func quorumTargets(up []time.Duration, down int) []*target {
+	var targets []*target
+	now := time.Now()
+	for _, l := range up {
+		tg := newTestTarget()
+		tg.observe(checkResult{Time: now, Connected: true, Latency: l}, now)
+		targets = append(targets, tg)
+	}
+	for range down {
+		tg := newTestTarget()
+		tg.observe(checkResult{Time: now, Connected: false}, now)
+		targets = append(targets, tg)
+	}
+	return targets
+}
+
+func TestQuorumVerdict(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		up      []time.Duration
+		down    int
+		n       int
+		wantUp  bool
+		latency time.Duration
+	}{
+		{"one of three is enough for 1", []time.Duration{10 * ms}, 2, 1, true, 10 * ms},
+		{"exactly the quorum", []time.Duration{10 * ms, 30 * ms}, 1, 2, true, 20 * ms},
+		{"one short", []time.Duration{10 * ms}, 2, 2, false, 10 * ms},
+		{"quorum of all", []time.Duration{10 * ms, 20 * ms, 60 * ms}, 0, 3, true, 20 * ms},
+		{"quorum of all with one down", []time.Duration{10 * ms, 20 * ms}, 1, 3, false, 15 * ms},
+		{"nothing up", nil, 3, 1, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := quorumVerdict(quorumTargets(tt.up, tt.down), tt.n)
+			if q.up != tt.wantUp || q.ok != len(tt.up) || q.total != len(tt.up)+tt.down || q.latency != tt.latency {
+				t.Errorf("got up %v, %d/%d, latency %s, want up %v, %d/%d, latency %s",
+					q.up, q.ok, q.total, q.latency, tt.wantUp, len(tt.up), len(tt.up)+tt.down, tt.latency)
+			}
+		})
+	}
+}
+
+func TestQuorumLatencySkipsFailedChecks(t *testing.T) {
+	targets := quorumTargets([]time.Duration{40 * time.Millisecond, 60 * time.Millisecond}, 0)
+
+	// The first target fails once but stays up under its threshold
+	flaky := targets[0]
+	flaky.failureThreshold = 3
+	now := time.Now()
+	flaky.observe(checkResult{Time: now, Connected: false}, now)
+	if !flaky.lastStatus {
+		t.Fatal("target went down below its failure threshold")
+	}
+
+	q := quorumVerdict(targets, 2)
+	if !q.up || q.ok != 2 {
+		t.Errorf("got up %v with %d up, want up with 2", q.up, q.ok)
+	}
+	if q.latency != 60*time.Millisecond {
+		t.Errorf("latency = %s, want 60ms from the only successful check", q.latency)
+	}
+}
+
+func TestQuorumStaleTargets(t *testing.T) {
+	targets := quorumTargets([]time.Duration{time.Millisecond, time.Millisecond}, 0)
+	targets[1].staleAfter = time.Second
+	targets[1].lastCheckTime = time.Now().Add(-time.Minute)
+	if q := quorumVerdict(targets, 2); q.up || q.ok != 1 {
+		t.Errorf("got up %v with %d up, want the stale target left out", q.up, q.ok)
+	}
+}
+
+func TestConnectionUp(t *testing.T) {
+	now := time.Now()
+	targets := quorumTargets([]time.Duration{time.Millisecond, time.Millisecond}, 1)
+	if connectionUp(targets, 0, now) {
+		t.Error("up without -quorum while a target is down")
+	}
+	if !connectionUp(targets, 2, now) {
+		t.Error("down with 2 of 3 targets up and -quorum 2")
+	}
+	if connectionUp(targets, 3, now) {
+		t.Error("up with 2 of 3 targets up and -quorum 3")
+	}
+	if !connectionUp(append(targets[:2], newTestTarget()), 0, now) {
+		t.Error("a target not checked yet counts as down")
+	}
+}
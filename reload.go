@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// liveOptions are the options a SIGHUP reload applies without a restart
+var liveOptions = []string{
+	"interval", "max-interval", "jitter",
+	"failures-threshold", "recovery-threshold", "latency-warn", "latency-good", "latency-bad", "trend-deadband",
+	"url", "target", "targets-file",
+}
+
+// reloadConfig re-reads the -config file at path into fs, leaving alone the
+// options in pinned, which were set on the command line or in the
+// environment. Live options take their new value, other changed options are
+// returned in restart as needing one. revert undoes the changes, for when
+// the new values turn out to be invalid together.
+func reloadConfig(path string, fs *flag.FlagSet, pinned map[string]bool) (restart []string, revert func(), err error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	old := make(map[string][]string)
+	revert = func() {
+		for key, values := range old {
+			setOption(fs, key, values)
+		}
+	}
+
+	keys := make([]string, 0, len(cfg))
+	for key := range cfg {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		f := fs.Lookup(key)
+		if f == nil || slices.Contains(configOnlyFlags, key) {
+			revert()
+			return nil, nil, fmt.Errorf("invalid config: %s: unknown option", key)
+		}
+		if pinned[key] || cfg[key] == nil {
+			continue
+		}
+		values := []string{fmt.Sprint(cfg[key])}
+		if list, ok := cfg[key].([]any); ok {
+			values = values[:0]
+			for _, v := range list {
+				values = append(values, fmt.Sprint(v))
+			}
+		}
+		// Set and compare the flag's own formatting, so 1m and 60s are the same
+		current := optionValues(f)
+		if err := setOption(fs, key, values); err != nil {
+			setOption(fs, key, current)
+			revert()
+			return nil, nil, fmt.Errorf("invalid config: %s: %w", key, err)
+		}
+		if slices.Equal(optionValues(f), current) {
+			continue
+		}
+		if !slices.Contains(liveOptions, key) {
+			setOption(fs, key, current)
+			restart = append(restart, key)
+			continue
+		}
+		old[key] = current
+	}
+	return restart, revert, nil
+}
+
+// optionValues returns the values of a flag, one per entry for a list
+func optionValues(f *flag.Flag) []string {
+	if l, ok := f.Value.(listFlag); ok {
+		return slices.Clone(l.Values())
+	}
+	return []string{f.Value.String()}
+}
+
+// setOption replaces the value of a flag, or every entry of a list
+func setOption(fs *flag.FlagSet, key string, values []string) error {
+	if l, ok := fs.Lookup(key).Value.(listFlag); ok {
+		l.Reset()
+	}
+	for _, v := range values {
+		if err := fs.Set(key, v); err != nil {
+			return fmt.Errorf("invalid value %s", strings.TrimSpace(v))
+		}
+	}
+	return nil
+}
+
+// validateLive checks the live options that can't be validated one at a
+// time, after a reload set them
+func validateLive(interval time.Duration, failuresThreshold, recoveryThreshold int, jitter float64, latencyGood, latencyBad time.Duration) error {
+	switch {
+	case interval <= 0:
+		return fmt.Errorf("invalid interval %s: must be positive", interval)
+	case failuresThreshold < 1:
+		return fmt.Errorf("invalid failures-threshold %d: must be at least 1", failuresThreshold)
+	case recoveryThreshold < 1:
+		return fmt.Errorf("invalid recovery-threshold %d: must be at least 1", recoveryThreshold)
+	case jitter < 0 || jitter > 100:
+		return fmt.Errorf("invalid jitter %g: must be between 0 and 100", jitter)
+	case latencyGood > latencyBad:
+		return fmt.Errorf("invalid latency-good %s: must not exceed latency-bad %s", latencyGood, latencyBad)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newReloadFlags defines live and restart-only flags, lists among both
+func newReloadFlags() (*flag.FlagSet, *urlList, *headerList) {
+	var urls urlList
+	var headers headerList
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&urls, "url", "")
+	fs.Var(&headers, "header", "")
+	fs.Duration("interval", 2*time.Second, "")
+	fs.Int("failures-threshold", 1, "")
+	fs.String("user-agent", "", "")
+	fs.String("config", "", "")
+	return fs, &urls, &headers
+}
+
+// writeConfig replaces the config file at path
+func writeConfig(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startConfig writes the config file and applies it, as run does at startup
+func startConfig(t *testing.T, fs *flag.FlagSet, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "networkcheck.yaml")
+	writeConfig(t, path, lines...)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.apply(fs); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var reloadBase = []string{
+	"url: [https://a.example, https://b.example]",
+	"header: ['X-One: 1', 'X-Two: 2']",
+	"interval: 10s",
+	"user-agent: probe/1.0",
+}
+
+func TestReloadUnchanged(t *testing.T) {
+	fs, urls, headers := newReloadFlags()
+	path := startConfig(t, fs, reloadBase...)
+
+	for i := range 3 {
+		restart, _, err := reloadConfig(path, fs, nil)
+		if err != nil {
+			t.Fatalf("reload %d: %v", i+1, err)
+		}
+		if len(restart) != 0 {
+			t.Errorf("reload %d: restart needed for %v with nothing changed", i+1, restart)
+		}
+	}
+	if want := []string{"X-One: 1", "X-Two: 2"}; !slices.Equal(*headers, want) {
+		t.Errorf("headers after reloads = %q, want %q", *headers, want)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !slices.Equal(*urls, want) {
+		t.Errorf("urls after reloads = %q, want %q", *urls, want)
+	}
+}
+
+func TestReloadChanges(t *testing.T) {
+	fs, urls, headers := newReloadFlags()
+	path := startConfig(t, fs, reloadBase...)
+
+	writeConfig(t, path,
+		"url: [https://c.example]",
+		"header: ['X-Three: 3']",
+		"interval: 1m",
+		"failures-threshold: 3",
+		"user-agent: probe/2.0",
+	)
+	restart, revert, err := reloadConfig(path, fs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Live options take their new values, the rest keep the old ones
+	if want := []string{"header", "user-agent"}; !slices.Equal(restart, want) {
+		t.Errorf("restart = %v, want %v", restart, want)
+	}
+	if got := fs.Lookup("interval").Value.String(); got != "1m0s" {
+		t.Errorf("interval = %s, want 1m0s", got)
+	}
+	if got := fs.Lookup("failures-threshold").Value.String(); got != "3" {
+		t.Errorf("failures-threshold = %s, want 3", got)
+	}
+	if want := []string{"https://c.example"}; !slices.Equal(*urls, want) {
+		t.Errorf("urls = %q, want %q", *urls, want)
+	}
+	if want := []string{"X-One: 1", "X-Two: 2"}; !slices.Equal(*headers, want) {
+		t.Errorf("headers = %q, want the old %q until a restart", *headers, want)
+	}
+	if got := fs.Lookup("user-agent").Value.String(); got != "probe/1.0" {
+		t.Errorf("user-agent = %s, want the old probe/1.0 until a restart", got)
+	}
+
+	revert()
+	if got := fs.Lookup("interval").Value.String(); got != "10s" {
+		t.Errorf("interval after revert = %s, want 10s", got)
+	}
+	if got := fs.Lookup("failures-threshold").Value.String(); got != "1" {
+		t.Errorf("failures-threshold after revert = %s, want 1", got)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !slices.Equal(*urls, want) {
+		t.Errorf("urls after revert = %q, want %q", *urls, want)
+	}
+}
+
+func TestReloadSameValueDifferentSpelling(t *testing.T) {
+	fs, _, _ := newReloadFlags()
+	path := startConfig(t, fs, "interval: 1m")
+	writeConfig(t, path, "interval: 60s")
+	restart, _, err := reloadConfig(path, fs, nil)
+	if err != nil || len(restart) != 0 {
+		t.Errorf("reload = %v, %v, want no change", restart, err)
+	}
+}
+
+func TestReloadLeavesPinnedOptions(t *testing.T) {
+	fs, _, _ := newReloadFlags()
+	if err := fs.Parse([]string{"-interval", "30s"}); err != nil {
+		t.Fatal(err)
+	}
+	path := startConfig(t, fs, reloadBase...)
+	writeConfig(t, path, "interval: 1m")
+	if _, _, err := reloadConfig(path, fs, map[string]bool{"interval": true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.Lookup("interval").Value.String(); got != "30s" {
+		t.Errorf("interval = %s, want the command line's 30s", got)
+	}
+}
+
+func TestReloadInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config []string
+		want   string
+	}{
+		{"unknown option", []string{"interval: 1m", "colour: red"}, "colour: unknown option"},
+		{"config-only option", []string{"config: other.yaml"}, "config: unknown option"},
+		{"invalid value", []string{"url: [https://c.example]", "interval: soon"}, "interval: invalid value soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, urls, _ := newReloadFlags()
+			path := startConfig(t, fs, reloadBase...)
+			writeConfig(t, path, tt.config...)
+			_, _, err := reloadConfig(path, fs, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("reload error = %v, want %q", err, tt.want)
+			}
+			// Nothing is left half-applied
+			if got := fs.Lookup("interval").Value.String(); got != "10s" {
+				t.Errorf("interval = %s, want 10s", got)
+			}
+			if want := []string{"https://a.example", "https://b.example"}; !slices.Equal(*urls, want) {
+				t.Errorf("urls = %q, want %q", *urls, want)
+			}
+		})
+	}
+}
+
+func TestValidateLive(t *testing.T) {
+	ok := func() (time.Duration, int, int, float64, time.Duration, time.Duration) {
+		return time.Second, 1, 1, 10, 100 * time.Millisecond, time.Second
+	}
+	if err := validateLive(ok()); err != nil {
+		t.Fatalf("valid options rejected: %v", err)
+	}
+	tests := []struct {
+		name   string
+		modify func(interval *time.Duration, failures, recovery *int, jitter *float64, good, bad *time.Duration)
+	}{
+		{"zero interval", func(i *time.Duration, _, _ *int, _ *float64, _, _ *time.Duration) { *i = 0 }},
+		{"zero failures threshold", func(_ *time.Duration, f, _ *int, _ *float64, _, _ *time.Duration) { *f = 0 }},
+		{"zero recovery threshold", func(_ *time.Duration, _, r *int, _ *float64, _, _ *time.Duration) { *r = 0 }},
+		{"jitter over 100", func(_ *time.Duration, _, _ *int, j *float64, _, _ *time.Duration) { *j = 101 }},
+		{"good above bad", func(_ *time.Duration, _, _ *int, _ *float64, g, b *time.Duration) { *g = 2 * *b }},
+	}
+	for _, tt := range tests {
+		interval, failures, recovery, jitter, good, bad := ok()
+		tt.modify(&interval, &failures, &recovery, &jitter, &good, &bad)
+		if err := validateLive(interval, failures, recovery, jitter, good, bad); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// retryDelay is the pause between attempts of a retried check
+const retryDelay = 200 * time.Millisecond
+
+// retryProber retries a failed check a few times within the same tick,
+// smoothing over a single lost packet without stretching the interval
+type retryProber struct {
+	Prober
+	retries int
+
+	// budget bounds all attempts together, zero leaves them unbounded
+	budget time.Duration
+}
+
+// Probe returns the first successful attempt, or the last failed one once
+// the retries run out. Running out of budget is reported as a timeout.
+func (p *retryProber) Probe(ctx context.Context) checkResult {
+	if p.budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.budget)
+		defer cancel()
+	}
+	result := p.Prober.Probe(ctx)
+	for attempt := 1; attempt <= p.retries && !result.Connected; attempt++ {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				result.Err = fmt.Errorf("no success in %d attempts within %s: %w", attempt, p.budget, ctx.Err())
+				result.Reason = failureReason(result.Err)
+			}
+			return result
+		case <-time.After(retryDelay):
+		}
+		result = p.Prober.Probe(ctx)
+	}
+	return result
+}
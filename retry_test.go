@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first n requests with a 503, then succeeds
+func flakyServer(n int32) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= n {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		okHandler(w, r)
+	}))
+	return srv, &requests
+}
+
+func TestRetrySucceedsAfterFailure(t *testing.T) {
+	srv, requests := flakyServer(1)
+	defer srv.Close()
+
+	p := &retryProber{Prober: newTestProber(srv.Client(), srv.URL), retries: 2}
+	r := p.Probe(context.Background())
+	if !r.Connected {
+		t.Fatalf("check failed after retrying: %s %v", r.Reason, r.Err)
+	}
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want the successful attempt's 200", r.StatusCode)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("server saw %d requests, want 2", got)
+	}
+	// The latency is the successful attempt's, without the failed one or
+	// the pause before the retry
+	if r.Latency >= retryDelay {
+		t.Errorf("latency %s includes the retry delay", r.Latency)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, requests := flakyServer(10)
+	defer srv.Close()
+
+	p := &retryProber{Prober: newTestProber(srv.Client(), srv.URL), retries: 2}
+	r := p.Probe(context.Background())
+	if r.Connected {
+		t.Fatal("check succeeded though every attempt failed")
+	}
+	if r.Reason != "HTTP 503" {
+		t.Errorf("reason = %q, want the last attempt's HTTP 503", r.Reason)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("server saw %d requests, want 3", got)
+	}
+}
+
+func TestRetryNotNeeded(t *testing.T) {
+	srv, requests := flakyServer(0)
+	defer srv.Close()
+
+	p := &retryProber{Prober: newTestProber(srv.Client(), srv.URL), retries: 3}
+	if r := p.Probe(context.Background()); !r.Connected {
+		t.Fatalf("check failed: %v", r.Err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want 1", got)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	srv, _ := flakyServer(100)
+	defer srv.Close()
+
+	p := &retryProber{Prober: newTestProber(srv.Client(), srv.URL), retries: 10, budget: 3 * retryDelay / 2}
+	start := time.Now()
+	r := p.Probe(context.Background())
+	if elapsed := time.Since(start); elapsed > 2*retryDelay {
+		t.Errorf("retries ran for %s, past the %s budget", elapsed, p.budget)
+	}
+	if r.Connected || r.Reason != "TIMEOUT" {
+		t.Errorf("result = connected %v, reason %q, want a TIMEOUT failure", r.Connected, r.Reason)
+	}
+}
+
+// slowServer answers after delay, or the first n requests after delay and
+// the rest at once when n is positive
+func slowServer(delay time.Duration, n int32) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n <= 0 || requests.Add(1) <= n {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		okHandler(w, r)
+	}))
+	return srv, &requests
+}
+
+func TestAttemptTimeoutRetries(t *testing.T) {
+	srv, requests := slowServer(time.Second, 1)
+	defer srv.Close()
+
+	// The first attempt times out on its own, leaving budget to retry
+	prober := newTestProber(srv.Client(), srv.URL)
+	prober.timeout = 100 * time.Millisecond
+	p := &retryProber{Prober: prober, retries: 2, budget: 2 * time.Second}
+	start := time.Now()
+	r := p.Probe(context.Background())
+	if !r.Connected {
+		t.Fatalf("check failed: %s %v", r.Reason, r.Err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("server saw %d requests, want 2", got)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("check took %s, waiting out the slow attempt", elapsed)
+	}
+}
+
+func TestTimeoutBoundsAttempts(t *testing.T) {
+	tests := []struct {
+		name           string
+		attemptTimeout time.Duration
+		budget         time.Duration
+	}{
+		// Runs out during the pause after the first attempt timed out
+		{"budget spent between attempts", 300 * time.Millisecond, 400 * time.Millisecond},
+		// Cuts the first attempt short of its own timeout
+		{"budget shorter than an attempt", time.Second, 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := slowServer(5*time.Second, 0)
+			defer srv.Close()
+
+			prober := newTestProber(srv.Client(), srv.URL)
+			prober.timeout = tt.attemptTimeout
+			p := &retryProber{Prober: prober, retries: 10, budget: tt.budget}
+			start := time.Now()
+			r := p.Probe(context.Background())
+			if elapsed := time.Since(start); elapsed > tt.budget+retryDelay {
+				t.Errorf("check ran for %s, past the %s budget", elapsed, tt.budget)
+			}
+			if r.Connected || r.Reason != "TIMEOUT" {
+				t.Errorf("result = connected %v, reason %q, want a TIMEOUT failure", r.Connected, r.Reason)
+			}
+			if r.Err == nil || !strings.Contains(r.Err.Error(), "within "+tt.budget.String()) {
+				t.Errorf("error = %v, want the budget reported", r.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// serveHTTP starts serving handler on addr in the background. The listener
+// is opened before returning so a bad address fails at startup.
+func serveHTTP(name, addr string, handler http.Handler) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server failed", "server", name, "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// shutdownHTTP stops server, giving in-flight requests a moment to finish
+func shutdownHTTP(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+}
+
+// statusSnapshot is the document served on /status. It is rebuilt by the
+// main loop after every round of checks and whenever the targets or their
+// statistics change otherwise:
+//
+//	{
+//	  "updated": "2006-01-02T15:04:05Z07:00",  // time of the snapshot
+//	  "connected": true,                       // same verdict as the exit code
+//	  "targets": [{
+//	    "url": "https://www.google.com",
+//	    "connected": true,                     // status after thresholds
+//	    "last_check": {...},                   // same as a -format json line
+//	    "uptime_ms": 0, "downtime_ms": 0, ...  // same as the exit summary
+//	  }]
+//	}
+type statusSnapshot struct {
+	Updated   string         `json:"updated"`
+	Connected bool           `json:"connected"`
+	Targets   []targetStatus `json:"targets"`
+}
+
+// targetStatus is one target's entry in a statusSnapshot
+type targetStatus struct {
+	Connected bool        `json:"connected"`
+	LastCheck checkRecord `json:"last_check"`
+	targetSummary
+}
+
+// newStatusSnapshot captures the current state of all targets, judging
+// the connection by quorum when it is set
+func newStatusSnapshot(targets []*target, quorum int, now time.Time) statusSnapshot {
+	snap := statusSnapshot{Updated: stamp(now), Connected: connectionUp(targets, quorum, now)}
+	for _, t := range targets {
+		snap.Targets = append(snap.Targets, targetStatus{
+			Connected:     t.lastStatus,
+			LastCheck:     newCheckRecord(t.url, t.last),
+			targetSummary: newTargetSummary(t),
+		})
+	}
+	return snap
+}
+
+// statusServer serves the monitor's own state over HTTP
+type statusServer struct {
+	mu       sync.RWMutex
+	snapshot statusSnapshot
+	server   *http.Server
+}
+
+func startStatusServer(addr string) (*statusServer, error) {
+	s := &statusServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+
+	var err error
+	if s.server, err = serveHTTP("status", addr, mux); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// update replaces the snapshot served to clients
+func (s *statusServer) update(snap statusSnapshot) {
+	s.mu.Lock()
+	s.snapshot = snap
+	s.mu.Unlock()
+}
+
+// handleStatus returns the latest statusSnapshot as JSON
+func (s *statusServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	snap := s.snapshot
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snap)
+}
+
+// handleHealthz returns 200 when the latest snapshot found the connection
+// up and 503 otherwise
+func (s *statusServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	connected := s.snapshot.Connected
+	s.mu.RUnlock()
+
+	if !connected {
+		http.Error(w, "disconnected", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (s *statusServer) Shutdown() {
+	shutdownHTTP(s.server)
+}
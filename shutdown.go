@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+)
+
+// teardown closes the sinks run opened once monitoring stops, so log files
+// are flushed and servers shut down before the process exits
+type teardown struct {
+	closers []io.Closer
+}
+
+// add registers c to be closed by close
+func (t *teardown) add(c io.Closer) {
+	t.closers = append(t.closers, c)
+}
+
+// close closes everything registered, most recent first, logging failures
+// rather than stopping at the first one
+func (t *teardown) close() {
+	for i := len(t.closers) - 1; i >= 0; i-- {
+		if err := t.closers[i].Close(); err != nil {
+			slog.Error("close failed", "error", err)
+		}
+	}
+	t.closers = nil
+}
+
+// closerFunc adapts a shutdown function without an error to io.Closer
+type closerFunc func()
+
+func (f closerFunc) Close() error {
+	f()
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestTeardownClosesEverything(t *testing.T) {
+	var logged bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logged, nil)))
+
+	var closed []string
+	closer := func(name string, err error) closerFuncErr {
+		return func() error {
+			closed = append(closed, name)
+			return err
+		}
+	}
+	var td teardown
+	td.add(closer("csv", nil))
+	td.add(closer("server", errors.New("server busy")))
+	td.add(closerFunc(func() { closed = append(closed, "mqtt") }))
+	td.close()
+
+	// Most recent first, and a failure doesn't stop the rest
+	if got := strings.Join(closed, ","); got != "mqtt,server,csv" {
+		t.Errorf("closed %s, want mqtt,server,csv", got)
+	}
+	if !strings.Contains(logged.String(), "server busy") {
+		t.Errorf("close failure not logged: %q", logged.String())
+	}
+
+	// A second close has nothing left to do
+	closed = nil
+	td.close()
+	if len(closed) != 0 {
+		t.Errorf("second close closed %v again", closed)
+	}
+}
+
+// closerFuncErr adapts a shutdown function that can fail to io.Closer
+type closerFuncErr func() error
+
+func (f closerFuncErr) Close() error {
+	return f()
+}
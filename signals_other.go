@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifyControlSignals returns channels that never receive, as the control
+// signals are Unix-only
+func notifyControlSignals() (dump, reset, hup, winch <-chan os.Signal) {
+	return nil, nil, nil, nil
+}
+
+// ignoreSIGPIPE does nothing, there is no SIGPIPE to ignore
+func ignoreSIGPIPE() {}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyControlSignals returns channels receiving the signals that control
+// a running monitor: SIGUSR1 dumps the current stats without stopping,
+// SIGUSR2 resets them, SIGHUP reloads -config and -targets-file, and
+// SIGWINCH means the terminal was resized and the display must adapt
+func notifyControlSignals() (dump, reset, hup, winch <-chan os.Signal) {
+	notify := func(sig os.Signal) <-chan os.Signal {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, sig)
+		return ch
+	}
+	return notify(syscall.SIGUSR1), notify(syscall.SIGUSR2), notify(syscall.SIGHUP), notify(syscall.SIGWINCH)
+}
+
+// ignoreSIGPIPE lets writes to a closed pipe fail with EPIPE instead of
+// killing the process
+func ignoreSIGPIPE() {
+	signal.Ignore(syscall.SIGPIPE)
+}
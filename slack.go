@@ -0,0 +1,41 @@
+package main
+
+// defaultSlackTemplate is the Slack message text unless -slack-template
+// overrides it; the indicator emoji is always prepended
+const defaultSlackTemplate = "{url} is *{status}* as of {time} (previous state lasted {duration})"
+
+// slackNotifier posts state changes to a Slack incoming webhook
+type slackNotifier struct {
+	url      string
+	template string
+	poster   *poster
+}
+
+// slackPayload is the body of a Slack incoming-webhook message
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func (n *slackNotifier) Notify(c stateChange) {
+	emoji := ":red_circle:"
+	switch c.To {
+	case stateUp:
+		emoji = ":large_green_circle:"
+	case stateDegraded:
+		emoji = ":large_yellow_circle:"
+	}
+	text := emoji + " " + expandTemplate(n.template, c)
+	n.poster.post("slack", n.url, slackPayload{Text: text})
+}
+
+func (n *slackNotifier) NotifySpike(s latencySpike) {
+	n.poster.post("slack", n.url, slackPayload{Text: ":warning: " + s.String()})
+}
+
+func (n *slackNotifier) NotifySustained(s sustainedLatency) {
+	n.poster.post("slack", n.url, slackPayload{Text: ":warning: " + s.String()})
+}
+
+func (n *slackNotifier) NotifyFlap(f flapAlert) {
+	n.poster.post("slack", n.url, slackPayload{Text: ":warning: " + f.String()})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"time"
+)
+
+// source is the local interface and/or address checks are sent from, the
+// zero value leaves the choice to the routing table
+type source struct {
+	ip     net.IP // local address to bind to, nil for any
+	device string // interface to bind to, empty for any
+
+	// addrs of the interface, from which ICMP picks one in the family of
+	// the host it pings
+	addrs []net.IP
+}
+
+// parseSource validates -interface and -source-ip against the machine's
+// interfaces
+func parseSource(iface, sourceIP string) (source, error) {
+	var s source
+	if iface != "" {
+		ifi, err := net.InterfaceByName(iface)
+		if err != nil {
+			return s, fmt.Errorf("invalid -interface %q: %w", iface, err)
+		}
+		if s.addrs, err = interfaceIPs(ifi); err != nil {
+			return s, fmt.Errorf("invalid -interface %q: %w", iface, err)
+		}
+		if err := checkBindToDevice(); err != nil {
+			return s, err
+		}
+		s.device = iface
+	}
+	if sourceIP != "" {
+		if s.ip = net.ParseIP(sourceIP); s.ip == nil {
+			return s, fmt.Errorf("invalid -source-ip %q: not an IP address", sourceIP)
+		}
+		addrs := s.addrs
+		if iface == "" {
+			ifaceAddrs, err := net.InterfaceAddrs()
+			if err != nil {
+				return s, err
+			}
+			addrs = prefixIPs(ifaceAddrs)
+		}
+		if !slices.ContainsFunc(addrs, s.ip.Equal) {
+			if iface != "" {
+				return s, fmt.Errorf("invalid -source-ip %s: not an address of %s", s.ip, iface)
+			}
+			return s, fmt.Errorf("invalid -source-ip %s: no local interface has this address", s.ip)
+		}
+		s.addrs = []net.IP{s.ip}
+	}
+	return s, nil
+}
+
+// interfaceIPs returns the addresses assigned to an interface
+func interfaceIPs(ifi *net.Interface) ([]net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	return prefixIPs(addrs), nil
+}
+
+// prefixIPs extracts the IPs of interface addresses, which come as prefixes
+func prefixIPs(addrs []net.Addr) []net.IP {
+	var ips []net.IP
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			ips = append(ips, n.IP)
+		}
+	}
+	return ips
+}
+
+// dialer returns a dialer bound to the source
+func (s source) dialer(timeout time.Duration) *net.Dialer {
+	d := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if s.ip != nil {
+		d.LocalAddr = &net.TCPAddr{IP: s.ip}
+	}
+	if s.device != "" {
+		d.Control = bindToDevice(s.device)
+	}
+	return d
+}
+
+// listenAddr returns the address an ICMP socket pinging an IPv4 or IPv6
+// host listens on, empty for any
+func (s source) listenAddr(ipv6 bool) string {
+	for _, ip := range s.addrs {
+		if (ip.To4() == nil) == ipv6 {
+			return ip.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkBindToDevice reports whether -interface is supported, which it
+// always is on Linux
+func checkBindToDevice() error {
+	return nil
+}
+
+// bindToDevice returns a dialer Control function binding sockets to an
+// interface with SO_BINDTODEVICE, so traffic leaves through it whatever
+// the routing table says
+func bindToDevice(device string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+		if ctrlErr := c.Control(func(fd uintptr) {
+			err = unix.BindToDevice(int(fd), device)
+		}); ctrlErr != nil {
+			return ctrlErr
+		}
+		return err
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// checkBindToDevice reports whether -interface is supported, which needs
+// SO_BINDTODEVICE
+func checkBindToDevice() error {
+	return errors.New("-interface is only supported on Linux, use -source-ip with one of its addresses instead")
+}
+
+// bindToDevice is never used where SO_BINDTODEVICE is unavailable
+func bindToDevice(string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import "strings"
+
+// sparkline renders the latencies of the most recent entries, at most width
+// of them, scaled between the lowest and highest successful latency shown
+func sparkline(entries []windowEntry, width int) string {
+	if len(entries) > width {
+		entries = entries[len(entries)-width:]
+	}
+
+	first := true
+	var lo, hi int64
+	for _, e := range entries {
+		if !e.ok {
+			continue
+		}
+		l := int64(e.latency)
+		if first || l < lo {
+			lo = l
+		}
+		if first || l > hi {
+			hi = l
+		}
+		first = false
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		if !e.ok {
+			b.WriteRune(symbols.gap)
+			continue
+		}
+		level := 0
+		if hi > lo {
+			level = int((int64(e.latency) - lo) * int64(len(symbols.spark)-1) / (hi - lo))
+		}
+		b.WriteRune(symbols.spark[level])
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// latencySpike is a check much slower than the target's usual latency
+type latencySpike struct {
+	URL     string
+	Time    time.Time
+	Latency time.Duration
+	Mean    time.Duration // baseline before the check
+	StdDev  time.Duration
+	Unit    string // -latency-unit String formats latencies in
+}
+
+func (s latencySpike) String() string {
+	return fmt.Sprintf("%s latency spiked to %s (baseline %s ± %s)", s.URL, formatLatency(s.Latency, s.Unit), formatLatency(s.Mean, s.Unit), formatLatency(s.StdDev, s.Unit))
+}
+
+// spikeNotifier is implemented by notifiers that also alert on latency
+// spikes, on top of state changes
+type spikeNotifier interface {
+	NotifySpike(s latencySpike)
+}
+
+// detectSpike reports whether a successful check's latency exceeds the
+// target's baseline by more than sigma standard deviations. The baseline is
+// the successful checks in the -window of recent ones, and needs warmup of
+// them before it is trusted. It must run before the check is observed so
+// the check isn't part of its own baseline.
+func (t *target) detectSpike(r checkResult, sigma float64, warmup int, unit string) (latencySpike, bool) {
+	if !r.Connected {
+		return latencySpike{}, false
+	}
+	baseline := t.recent.LatencyStats()
+	if baseline.n < warmup {
+		return latencySpike{}, false
+	}
+	mean, stddev := baseline.mean, baseline.stddev()
+	if float64(r.Latency) <= mean+sigma*stddev {
+		return latencySpike{}, false
+	}
+	return latencySpike{
+		URL:     t.url,
+		Time:    r.Time,
+		Latency: r.Latency,
+		Mean:    time.Duration(mean),
+		StdDev:  time.Duration(stddev),
+		Unit:    unit,
+	}, true
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// savedState is what -state-file keeps between runs, so accumulated
+// statistics survive a restart
+type savedState struct {
+	SavedAt time.Time              `json:"saved_at"`
+	Targets map[string]savedTarget `json:"targets"` // keyed by URL
+}
+
+// savedTarget holds a target's aggregates, durations in nanoseconds
+type savedTarget struct {
+	Uptime         time.Duration   `json:"uptime"`
+	DegradedTime   time.Duration   `json:"degraded_time"`
+	Downtime       time.Duration   `json:"downtime"`
+	Incidents      []incident      `json:"incidents"`
+	MinLatency     time.Duration   `json:"min_latency"`
+	MaxLatency     time.Duration   `json:"max_latency"`
+	TotalLatency   time.Duration   `json:"total_latency"`
+	LatencyCount   int             `json:"latency_count"`
+	LatencyMean    float64         `json:"latency_mean"`
+	LatencyM2      float64         `json:"latency_m2"`
+	LatencySamples []time.Duration `json:"latency_samples"`
+	Failures       map[string]int  `json:"failures,omitempty"`
+}
+
+// loadState reads a -state-file, returning nil without error when it
+// doesn't exist yet
+func loadState(path string) (*savedState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s savedState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// saveState writes the targets' aggregates to path through a temporary
+// file renamed into place, so a crash mid-write never leaves a truncated
+// state behind
+func saveState(path string, targets []*target, now time.Time) error {
+	s := savedState{SavedAt: now, Targets: make(map[string]savedTarget, len(targets))}
+	for _, t := range targets {
+		s.Targets[t.url] = t.snapshot(now)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// snapshot returns the aggregates to save for the target as of now. An
+// outage still going on is cut off at now, the next run tracks it afresh.
+func (t *target) snapshot(now time.Time) savedTarget {
+	uptime, degraded, downtime := t.totals()
+	incidents := make([]incident, len(t.incidents))
+	for i, inc := range t.incidents {
+		if inc.ongoing() {
+			inc.End = now
+		}
+		incidents[i] = inc
+	}
+	return savedTarget{
+		Uptime:         uptime,
+		DegradedTime:   degraded,
+		Downtime:       downtime,
+		Incidents:      incidents,
+		MinLatency:     t.minLatency,
+		MaxLatency:     t.maxLatency,
+		TotalLatency:   t.totalLatency,
+		LatencyCount:   t.latencyCount,
+		LatencyMean:    t.latencyDev.mean,
+		LatencyM2:      t.latencyDev.m2,
+		LatencySamples: t.latencySamples,
+		Failures:       t.failures,
+	}
+}
+
+// restore continues from saved aggregates, before the first check
+func (t *target) restore(s savedTarget) {
+	t.uptime, t.degradedTime, t.downtime = s.Uptime, s.DegradedTime, s.Downtime
+	t.incidents = s.Incidents
+	t.minLatency, t.maxLatency, t.totalLatency = s.MinLatency, s.MaxLatency, s.TotalLatency
+	t.latencyCount = s.LatencyCount
+	t.latencyDev = welford{n: s.LatencyCount, mean: s.LatencyMean, m2: s.LatencyM2}
+	t.latencySamples = s.LatencySamples
+	if t.failures != nil {
+		maps.Copy(t.failures, s.Failures)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	tg := newTestTarget()
+	tg.failures = make(failureTally)
+	for i, c := range []check{{0, true}, {2, true}, {4, false}, {6, false}, {8, true}, {10, true}} {
+		now := testStart.Add(time.Duration(c.at) * time.Second)
+		r := checkResult{Time: now, Connected: c.ok, Latency: time.Duration(10*(i+1)) * time.Millisecond}
+		if !c.ok {
+			r.Reason = "TIMEOUT"
+		}
+		tg.observe(r, now)
+	}
+	savedAt := testStart.Add(10 * time.Second)
+	want := tg.snapshot(savedAt)
+
+	if err := saveState(path, []*target{tg}, savedAt); err != nil {
+		t.Fatalf("saveState: %v", err)
+	}
+	s, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState: %v", err)
+	}
+	if !s.SavedAt.Equal(savedAt) {
+		t.Errorf("SavedAt = %s, want %s", s.SavedAt, savedAt)
+	}
+	saved, ok := s.Targets[tg.url]
+	if !ok {
+		t.Fatalf("no state saved for %s", tg.url)
+	}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("loaded\n%+v\nwant\n%+v", saved, want)
+	}
+
+	restored := newTestTarget()
+	restored.failures = make(failureTally)
+	restored.restore(saved)
+	if got := restored.snapshot(savedAt); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot after restore\n%+v\nwant\n%+v", got, want)
+	}
+	if restored.avgLatency() != tg.avgLatency() || restored.latencyDev.stddev() != tg.latencyDev.stddev() {
+		t.Errorf("restored avg %s, stddev %v, want %s, %v",
+			restored.avgLatency(), restored.latencyDev.stddev(), tg.avgLatency(), tg.latencyDev.stddev())
+	}
+
+	// No temporary files are left next to the state
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("state directory holds %d files, want only the state file", len(entries))
+	}
+}
+
+func TestSnapshotCutsOffOngoingOutage(t *testing.T) {
+	tg := newTestTarget()
+	feed(tg, []check{{0, true}, {2, false}, {4, false}})
+	now := testStart.Add(5 * time.Second)
+	s := tg.snapshot(now)
+	if len(s.Incidents) != 1 || !s.Incidents[0].End.Equal(now) {
+		t.Fatalf("incidents = %+v, want one ending at %s", s.Incidents, now)
+	}
+	if !tg.incidents[0].ongoing() {
+		t.Error("snapshot ended the target's own ongoing incident")
+	}
+}
+
+func TestLoadStateMissing(t *testing.T) {
+	s, err := loadState(filepath.Join(t.TempDir(), "missing.json"))
+	if s != nil || err != nil {
+		t.Errorf("loadState of a missing file = %v, %v, want nil, nil", s, err)
+	}
+}
+
+func TestLoadStateCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(path); err == nil {
+		t.Error("loadState of a corrupt file succeeded")
+	}
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// maxLatencySamples bounds the latencies kept per target for percentiles;
+// beyond it the samples are maintained as a uniform reservoir sample
+const maxLatencySamples = 10000
+
+// target is a monitored URL along with its own status and statistics
+type target struct {
+	url    string
+	prober Prober
+	last   checkResult
+
+	// Checks still to be excluded from the statistics, while caches are cold
+	warmup int
+
+	// How long the status of the last check holds in the overall verdict
+	// with -rotate, zero when it never goes stale
+	staleAfter time.Duration
+
+	// Consecutive failed checks needed before the target counts as down
+	failureThreshold    int
+	consecutiveFailures int
+
+	// Consecutive successful checks needed before a down target counts as
+	// recovered
+	recoveryThreshold    int
+	consecutiveSuccesses int
+
+	// Latency above which a successful check counts as degraded, zero
+	// disables the degraded state
+	latencyWarn time.Duration
+
+	// Status tracking. uptime, degradedTime and downtime only cover states
+	// that have ended, use totals to include the state in progress.
+	lastStatus       bool
+	degraded         bool
+	statusChangeTime time.Time
+	lastCheckTime    time.Time
+	uptime           time.Duration
+	degradedTime     time.Duration
+	downtime         time.Duration
+
+	// Outages in the order they started, the last one may be ongoing
+	incidents []incident
+
+	// Outcomes of the most recent checks
+	recent *window
+
+	// Recent state changes, to detect flapping
+	flaps flapDetector
+
+	// Latency statistics
+	minLatency   time.Duration
+	maxLatency   time.Duration
+	totalLatency time.Duration
+	latencyCount int
+	latencyDev   welford
+
+	// Current run of checks slower than -sustained-latency
+	sustained sustainedStreak
+
+	// Smoothed latency of successful checks
+	latencyEWMA ewma
+
+	// Latency samples for percentiles
+	latencySamples []time.Duration
+
+	// Download speeds in -throughput mode
+	throughput throughputStats
+
+	// Failed checks by category, nil unless -retry-summary is set
+	failures failureTally
+
+	// Probes sent and lost over all -burst checks
+	probesSent int
+	probesLost int
+}
+
+// incident is a single outage of a target
+type incident struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"` // zero while the outage is ongoing
+}
+
+// ongoing reports whether the outage hasn't ended yet
+func (i incident) ongoing() bool {
+	return i.End.IsZero()
+}
+
+// duration returns how long the outage lasted, or has lasted as of asOf if
+// it is still ongoing
+func (i incident) duration(asOf time.Time) time.Duration {
+	if i.ongoing() {
+		return asOf.Sub(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// connState is a target's overall state as shown to the user
+type connState int
+
+const (
+	stateDown connState = iota
+	stateUp
+	stateDegraded // connected, but slower than -latency-warn
+)
+
+func (s connState) String() string {
+	switch s {
+	case stateUp:
+		return "up"
+	case stateDegraded:
+		return "degraded"
+	}
+	return "down"
+}
+
+// stateChange describes a target switching between states
+type stateChange struct {
+	URL      string
+	From     connState
+	To       connState
+	Time     time.Time     // when the check that saw the change ran
+	Previous time.Duration // how long the state just left lasted
+}
+
+// recovered reports whether the change ends an outage
+func (c stateChange) recovered() bool {
+	return c.From == stateDown && c.To != stateDown
+}
+
+// state returns the target's current state
+func (t *target) state() connState {
+	switch {
+	case !t.lastStatus:
+		return stateDown
+	case t.degraded:
+		return stateDegraded
+	}
+	return stateUp
+}
+
+// observe folds a check result taken at now into the target's statistics,
+// returning the state change it caused, if any
+func (t *target) observe(r checkResult, now time.Time) *stateChange {
+	// A -warmup check only sets the status shown, leaving the target as if
+	// it hadn't been checked yet
+	if t.warmup > 0 {
+		t.warmup--
+		t.lastStatus = r.Connected
+		t.degraded = r.Connected && t.latencyWarn > 0 && r.Latency > t.latencyWarn
+		t.last = r
+		return nil
+	}
+
+	// Isolated failures or successes keep the previous status until enough
+	// of them in a row confirm the outage or the recovery
+	connected := r.Connected
+	if r.Connected {
+		t.consecutiveFailures = 0
+		t.consecutiveSuccesses++
+		if !t.statusChangeTime.IsZero() && t.consecutiveSuccesses < t.recoveryThreshold {
+			connected = t.lastStatus
+		}
+	} else {
+		t.consecutiveSuccesses = 0
+		t.consecutiveFailures++
+		if !t.statusChangeTime.IsZero() && t.consecutiveFailures < t.failureThreshold {
+			connected = t.lastStatus
+		}
+	}
+
+	// A slow success degrades a connected target, while a failure that
+	// didn't bring it down leaves it as it was
+	degraded := false
+	if connected {
+		if r.Connected {
+			degraded = t.latencyWarn > 0 && r.Latency > t.latencyWarn
+		} else {
+			degraded = t.degraded
+		}
+	}
+
+	// Time since the last transition belongs to the previous state until a
+	// check sees the status change
+	var change *stateChange
+	if t.statusChangeTime.IsZero() {
+		t.lastStatus, t.degraded = connected, degraded
+		t.statusChangeTime = now
+		if !connected {
+			t.incidents = append(t.incidents, incident{Start: now})
+		}
+	} else if from := t.state(); connected != t.lastStatus || degraded != t.degraded {
+		previous := now.Sub(t.statusChangeTime)
+		switch from {
+		case stateUp:
+			t.uptime += previous
+		case stateDegraded:
+			t.degradedTime += previous
+		default:
+			t.downtime += previous
+		}
+		t.lastStatus, t.degraded = connected, degraded
+		t.statusChangeTime = now
+		if connected != (from != stateDown) {
+			if connected {
+				t.incidents[len(t.incidents)-1].End = now
+			} else {
+				t.incidents = append(t.incidents, incident{Start: now})
+			}
+		}
+		change = &stateChange{URL: t.url, From: from, To: t.state(), Time: now, Previous: previous}
+	}
+
+	t.recent.Push(r.Connected, r.Latency)
+	if !r.Connected && t.failures != nil {
+		t.failures[failureCategory(r)]++
+	}
+	if r.Burst != nil {
+		t.probesSent += r.Burst.Sent
+		t.probesLost += r.Burst.Lost
+	}
+
+	// Update latency statistics
+	if r.Connected && r.Latency > 0 {
+		if t.latencyCount == 0 || r.Latency < t.minLatency {
+			t.minLatency = r.Latency
+		}
+		if r.Latency > t.maxLatency {
+			t.maxLatency = r.Latency
+		}
+		t.totalLatency += r.Latency
+		t.latencyCount++
+		t.latencyDev.add(float64(r.Latency))
+		t.latencyEWMA.Add(r.Latency)
+
+		if len(t.latencySamples) < maxLatencySamples {
+			t.latencySamples = append(t.latencySamples, r.Latency)
+		} else if i := rand.Intn(t.latencyCount); i < maxLatencySamples {
+			t.latencySamples[i] = r.Latency
+		}
+	}
+
+	if r.Connected && r.Mbps > 0 {
+		t.throughput.add(r.Mbps)
+	}
+
+	t.lastCheckTime = now
+	t.last = r
+	return change
+}
+
+// up reports whether the target counts as connected in the overall verdict
+// as of now: its status is up and, with -rotate, not stale
+func (t *target) up(now time.Time) bool {
+	return t.lastStatus && (t.staleAfter == 0 || now.Sub(t.lastCheckTime) <= t.staleAfter)
+}
+
+// warmingUp reports whether the latest check was a -warmup check
+func (t *target) warmingUp() bool {
+	return t.statusChangeTime.IsZero() && !t.last.Time.IsZero()
+}
+
+// recovering describes the successful checks of a down target that haven't
+// yet reached -recovery-threshold, e.g. "recovering 1/3"
+func (t *target) recovering() (string, bool) {
+	if t.lastStatus || t.consecutiveSuccesses == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("recovering %d/%d", t.consecutiveSuccesses, t.recoveryThreshold), true
+}
+
+// reset zeroes the target's accumulated statistics as of now, keeping its
+// current state, which from then on counts as having started at now
+func (t *target) reset(now time.Time) {
+	t.uptime, t.degradedTime, t.downtime = 0, 0, 0
+	t.incidents = nil
+	if !t.statusChangeTime.IsZero() {
+		t.statusChangeTime = now
+		t.lastCheckTime = now
+		if !t.lastStatus {
+			t.incidents = append(t.incidents, incident{Start: now})
+		}
+	}
+	t.recent.Reset()
+	t.flaps.Reset()
+	t.minLatency, t.maxLatency, t.totalLatency = 0, 0, 0
+	t.latencyCount = 0
+	t.latencyDev = welford{}
+	t.sustained = sustainedStreak{}
+	t.latencyEWMA.Reset()
+	t.latencySamples = nil
+	t.throughput = throughputStats{}
+	clear(t.failures)
+	t.probesSent, t.probesLost = 0, 0
+}
+
+// resume moves the current state's start past a pause of the given length,
+// so paused time counts as neither uptime nor downtime
+func (t *target) resume(paused time.Duration) {
+	if t.statusChangeTime.IsZero() {
+		return
+	}
+	t.statusChangeTime = t.statusChangeTime.Add(paused)
+	t.lastCheckTime = t.lastCheckTime.Add(paused)
+}
+
+// totals returns the clean uptime, degraded time and downtime including the
+// state in progress as of the last check
+func (t *target) totals() (uptime, degraded, downtime time.Duration) {
+	uptime, degraded, downtime = t.uptime, t.degradedTime, t.downtime
+	current := t.lastCheckTime.Sub(t.statusChangeTime)
+	switch t.state() {
+	case stateUp:
+		uptime += current
+	case stateDegraded:
+		degraded += current
+	default:
+		downtime += current
+	}
+	return uptime, degraded, downtime
+}
+
+// avgLatency returns the mean latency of successful checks
+func (t *target) avgLatency() time.Duration {
+	if t.latencyCount == 0 {
+		return 0
+	}
+	return t.totalLatency / time.Duration(t.latencyCount)
+}
+
+// outageStats returns the longest and average outage duration, counting an
+// ongoing outage up to the last check
+func (t *target) outageStats() (longest, average time.Duration) {
+	if len(t.incidents) == 0 {
+		return 0, 0
+	}
+	var total time.Duration
+	for _, i := range t.incidents {
+		d := i.duration(t.lastCheckTime)
+		total += d
+		longest = max(longest, d)
+	}
+	return longest, total / time.Duration(len(t.incidents))
+}
+
+// lastOutage returns the most recent outage that has ended, and false if
+// none has
+func (t *target) lastOutage() (incident, bool) {
+	for i := len(t.incidents) - 1; i >= 0; i-- {
+		if !t.incidents[i].ongoing() {
+			return t.incidents[i], true
+		}
+	}
+	return incident{}, false
+}
+
+// jitter returns the standard deviation of successful-check latencies
+func (t *target) jitter() time.Duration {
+	return time.Duration(t.latencyDev.stddev())
+}
+
+// uptimePercent returns the share of tracked time the target was connected,
+// degraded or not, and false if no time has been tracked yet
+func (t *target) uptimePercent() (float64, bool) {
+	uptime, degraded, downtime := t.totals()
+	total := uptime + degraded + downtime
+	if total == 0 {
+		return 0, false
+	}
+	return float64(uptime+degraded) / float64(total) * 100, true
+}
+
+// percentile returns the p-th percentile (0-100) of samples, interpolating
+// linearly between the closest ranks
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	frac := rank - float64(lo)
+	return sorted[lo] + time.Duration(frac*float64(sorted[hi]-sorted[lo]))
+}
+
+// welford keeps a running mean and variance using Welford's online
+// algorithm, so no samples need to be stored
+type welford struct {
+	n    int
+	mean float64
+	m2   float64
+}
+
+func (w *welford) add(x float64) {
+	w.n++
+	delta := x - w.mean
+	w.mean += delta / float64(w.n)
+	w.m2 += delta * (x - w.mean)
+}
+
+// stddev returns the population standard deviation of the values added so far
+func (w *welford) stddev() float64 {
+	if w.n == 0 {
+		return 0
+	}
+	return math.Sqrt(w.m2 / float64(w.n))
+}
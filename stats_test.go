@@ -0,0 +1,361 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestTarget returns a target that goes down on the first failure and
+// recovers on the first success
+func newTestTarget() *target {
+	return &target{
+		url:               "http://example.com",
+		failureThreshold:  1,
+		recoveryThreshold: 1,
+		recent:            newWindow(10),
+	}
+}
+
+// check is one scripted check: its outcome and when it ran, in seconds
+// after testStart
+type check struct {
+	at int
+	ok bool
+}
+
+// feed observes each scripted check in turn
+func feed(t *target, checks []check) {
+	for _, c := range checks {
+		now := testStart.Add(time.Duration(c.at) * time.Second)
+		t.observe(checkResult{Time: now, Connected: c.ok, Latency: 10 * time.Millisecond}, now)
+	}
+}
+
+func TestTotals(t *testing.T) {
+	tests := []struct {
+		name             string
+		checks           []check
+		uptime, downtime time.Duration
+	}{
+		{"single check", []check{{0, true}}, 0, 0},
+		{"always up", []check{{0, true}, {2, true}, {4, true}}, 4 * time.Second, 0},
+		{"always down", []check{{0, false}, {2, false}}, 0, 2 * time.Second},
+		{
+			name:     "down then up",
+			checks:   []check{{0, true}, {2, true}, {4, false}, {6, false}, {8, true}, {10, true}},
+			uptime:   6 * time.Second,
+			downtime: 4 * time.Second,
+		},
+		{
+			name:     "starts down",
+			checks:   []check{{0, false}, {3, true}, {5, true}},
+			uptime:   2 * time.Second,
+			downtime: 3 * time.Second,
+		},
+		{
+			name:     "ends down",
+			checks:   []check{{0, true}, {1, false}, {5, false}},
+			uptime:   time.Second,
+			downtime: 4 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestTarget()
+			feed(tg, tt.checks)
+			uptime, degraded, downtime := tg.totals()
+			if uptime != tt.uptime || downtime != tt.downtime || degraded != 0 {
+				t.Errorf("totals() = %s up, %s degraded, %s down, want %s up, %s down", uptime, degraded, downtime, tt.uptime, tt.downtime)
+			}
+		})
+	}
+}
+
+func TestObserveStateChanges(t *testing.T) {
+	tg := newTestTarget()
+	var changes []stateChange
+	for _, c := range []check{{0, true}, {2, true}, {4, false}, {6, false}, {9, true}} {
+		now := testStart.Add(time.Duration(c.at) * time.Second)
+		if change := tg.observe(checkResult{Time: now, Connected: c.ok}, now); change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	want := []stateChange{
+		{URL: tg.url, From: stateUp, To: stateDown, Time: testStart.Add(4 * time.Second), Previous: 4 * time.Second},
+		{URL: tg.url, From: stateDown, To: stateUp, Time: testStart.Add(9 * time.Second), Previous: 5 * time.Second},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d state changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestFailureThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		checks    []check
+		wantUp    bool
+		incidents int
+	}{
+		{"isolated failures stay up", 3, []check{{0, true}, {1, false}, {2, true}, {3, false}, {4, false}, {5, true}}, true, 0},
+		{"threshold reached goes down", 3, []check{{0, true}, {1, false}, {2, false}, {3, false}}, false, 1},
+		{"recovery is immediate", 3, []check{{0, true}, {1, false}, {2, false}, {3, false}, {4, true}}, true, 1},
+		{"threshold of one", 1, []check{{0, true}, {1, false}}, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestTarget()
+			tg.failureThreshold = tt.threshold
+			feed(tg, tt.checks)
+			if tg.lastStatus != tt.wantUp {
+				t.Errorf("lastStatus = %v, want %v", tg.lastStatus, tt.wantUp)
+			}
+			if len(tg.incidents) != tt.incidents {
+				t.Errorf("got %d incidents, want %d", len(tg.incidents), tt.incidents)
+			}
+		})
+	}
+}
+
+func TestFailureThresholdDowntimeStartsAtThreshold(t *testing.T) {
+	tg := newTestTarget()
+	tg.failureThreshold = 2
+	feed(tg, []check{{0, true}, {2, false}, {4, false}, {6, false}})
+	uptime, _, downtime := tg.totals()
+	if uptime != 4*time.Second || downtime != 2*time.Second {
+		t.Errorf("totals() = %s up, %s down, want 4s up, 2s down", uptime, downtime)
+	}
+}
+
+func TestRecoveryThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		threshold  int
+		checks     []check
+		wantUp     bool
+		recovering string
+	}{
+		{"noisy recovery stays down", 3, []check{{0, false}, {1, true}, {2, false}, {3, true}, {4, true}, {5, false}, {6, true}}, false, "recovering 1/3"},
+		{"threshold reached comes up", 3, []check{{0, true}, {1, false}, {2, true}, {3, false}, {4, true}, {5, true}, {6, true}}, true, ""},
+		{"short of the threshold", 3, []check{{0, false}, {1, true}, {2, true}}, false, "recovering 2/3"},
+		{"first check is up at once", 3, []check{{0, true}}, true, ""},
+		{"threshold of one", 1, []check{{0, false}, {1, true}}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestTarget()
+			tg.recoveryThreshold = tt.threshold
+			feed(tg, tt.checks)
+			if tg.lastStatus != tt.wantUp {
+				t.Errorf("lastStatus = %v, want %v", tg.lastStatus, tt.wantUp)
+			}
+			if got, _ := tg.recovering(); got != tt.recovering {
+				t.Errorf("recovering() = %q, want %q", got, tt.recovering)
+			}
+		})
+	}
+}
+
+func TestRecoveryThresholdReportsOneChange(t *testing.T) {
+	tg := newTestTarget()
+	tg.recoveryThreshold = 2
+	var changes []stateChange
+	for _, c := range []check{{0, true}, {2, false}, {4, true}, {6, false}, {8, true}, {10, true}, {12, true}} {
+		now := testStart.Add(time.Duration(c.at) * time.Second)
+		if change := tg.observe(checkResult{Time: now, Connected: c.ok, Latency: 10 * time.Millisecond}, now); change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	if len(changes) != 2 || changes[0].To != stateDown || changes[1].To != stateUp {
+		t.Fatalf("got changes %+v, want down then up", changes)
+	}
+	// The outage lasts until the check that confirmed the recovery
+	if !changes[1].Time.Equal(testStart.Add(10 * time.Second)) {
+		t.Errorf("recovered at %s, want 10s in", changes[1].Time.Sub(testStart))
+	}
+	if _, _, downtime := tg.totals(); downtime != 8*time.Second {
+		t.Errorf("downtime = %s, want 8s", downtime)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	ms := func(n ...int) []time.Duration {
+		var d []time.Duration
+		for _, v := range n {
+			d = append(d, time.Duration(v)*time.Millisecond)
+		}
+		return d
+	}
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		p       float64
+		want    time.Duration
+	}{
+		{"empty", nil, 50, 0},
+		{"single p50", ms(7), 50, 7 * time.Millisecond},
+		{"single p99", ms(7), 99, 7 * time.Millisecond},
+		{"odd median", ms(30, 10, 20), 50, 20 * time.Millisecond},
+		{"even median interpolates", ms(10, 20, 30, 40), 50, 25 * time.Millisecond},
+		{"p0 is min", ms(40, 10, 30), 0, 10 * time.Millisecond},
+		{"p100 is max", ms(40, 10, 30), 100, 40 * time.Millisecond},
+		{"p95 of 1..101", ms(seq(1, 101)...), 95, 96 * time.Millisecond},
+		{"clamped above 100", ms(1, 2), 150, 2 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.samples, tt.p); got != tt.want {
+				t.Errorf("percentile(%v, %g) = %s, want %s", tt.samples, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentileLeavesSamplesUnsorted(t *testing.T) {
+	samples := []time.Duration{3, 1, 2}
+	percentile(samples, 50)
+	if samples[0] != 3 || samples[1] != 1 || samples[2] != 2 {
+		t.Errorf("percentile reordered its input: %v", samples)
+	}
+}
+
+func seq(from, to int) []int {
+	var s []int
+	for i := from; i <= to; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+func TestJitter(t *testing.T) {
+	// Population standard deviation of 2, 4, 4, 4, 5, 5, 7, 9 ms is 2ms
+	tg := newTestTarget()
+	for i, ms := range []int{2, 4, 4, 4, 5, 5, 7, 9} {
+		now := testStart.Add(time.Duration(i) * time.Second)
+		tg.observe(checkResult{Time: now, Connected: true, Latency: time.Duration(ms) * time.Millisecond}, now)
+	}
+	if got, want := tg.jitter(), 2*time.Millisecond; math.Abs(float64(got-want)) > float64(time.Microsecond) {
+		t.Errorf("jitter() = %s, want %s", got, want)
+	}
+}
+
+func TestJitterIgnoresFailures(t *testing.T) {
+	tg := newTestTarget()
+	for i, r := range []checkResult{
+		{Connected: true, Latency: 10 * time.Millisecond},
+		{Connected: false},
+		{Connected: true, Latency: 10 * time.Millisecond},
+	} {
+		now := testStart.Add(time.Duration(i) * time.Second)
+		r.Time = now
+		tg.observe(r, now)
+	}
+	if got := tg.jitter(); got != 0 {
+		t.Errorf("jitter() = %s, want 0 for identical latencies", got)
+	}
+}
+
+func TestWelfordEmpty(t *testing.T) {
+	var w welford
+	if got := w.stddev(); got != 0 {
+		t.Errorf("stddev() of no values = %g, want 0", got)
+	}
+}
+
+func TestIncidents(t *testing.T) {
+	tg := newTestTarget()
+	feed(tg, []check{
+		{0, true},
+		{10, false}, {12, false}, {15, true},
+		{20, true},
+		{30, false}, {40, true},
+		{50, false}, {52, false},
+	})
+	at := func(s int) time.Time { return testStart.Add(time.Duration(s) * time.Second) }
+	want := []incident{
+		{Start: at(10), End: at(15)},
+		{Start: at(30), End: at(40)},
+		{Start: at(50)},
+	}
+	if len(tg.incidents) != len(want) {
+		t.Fatalf("got %d incidents, want %d: %+v", len(tg.incidents), len(want), tg.incidents)
+	}
+	for i := range want {
+		if tg.incidents[i] != want[i] {
+			t.Errorf("incident %d = %+v, want %+v", i, tg.incidents[i], want[i])
+		}
+	}
+	if !tg.incidents[2].ongoing() {
+		t.Error("last incident isn't ongoing")
+	}
+	if got := tg.incidents[2].duration(tg.lastCheckTime); got != 2*time.Second {
+		t.Errorf("ongoing incident duration = %s, want 2s", got)
+	}
+
+	longest, average := tg.outageStats()
+	if longest != 10*time.Second {
+		t.Errorf("longest outage = %s, want 10s", longest)
+	}
+	if average != 17*time.Second/3 {
+		t.Errorf("average outage = %s, want %s", average, 17*time.Second/3)
+	}
+	if last, ok := tg.lastOutage(); !ok || last != want[1] {
+		t.Errorf("lastOutage() = %+v, %v, want %+v", last, ok, want[1])
+	}
+}
+
+func TestIncidentsStartingDown(t *testing.T) {
+	tg := newTestTarget()
+	feed(tg, []check{{0, false}, {5, true}})
+	if len(tg.incidents) != 1 || tg.incidents[0].Start != testStart || tg.incidents[0].End != testStart.Add(5*time.Second) {
+		t.Errorf("incidents = %+v, want one from 0s to 5s", tg.incidents)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tg := newTestTarget()
+	tg.failures = make(failureTally)
+	tg.flaps = flapDetector{threshold: 1, window: time.Hour}
+	tg.latencyEWMA = ewma{alpha: 0.5}
+	feed(tg, []check{{0, true}, {5, false}, {8, true}, {10, false}})
+	tg.observe(checkResult{Time: testStart.Add(11 * time.Second), Connected: true, Latency: time.Millisecond, Mbps: 20, Burst: &burstStats{Sent: 4, Lost: 1}}, testStart.Add(11*time.Second))
+	tg.observe(checkResult{Time: testStart.Add(12 * time.Second), Connected: false}, testStart.Add(12*time.Second))
+
+	now := testStart.Add(20 * time.Second)
+	tg.reset(now)
+
+	if uptime, degraded, downtime := tg.totals(); uptime != 0 || degraded != 0 || downtime != 0 {
+		t.Errorf("totals() after reset = %s, %s, %s, want all 0", uptime, degraded, downtime)
+	}
+	if tg.latencyCount != 0 || tg.totalLatency != 0 || tg.minLatency != 0 || tg.maxLatency != 0 {
+		t.Errorf("latency stats not reset: count %d, total %s, min %s, max %s", tg.latencyCount, tg.totalLatency, tg.minLatency, tg.maxLatency)
+	}
+	if tg.jitter() != 0 || tg.latencyEWMA.Value() != 0 || len(tg.latencySamples) != 0 {
+		t.Error("jitter, smoothed latency or samples not reset")
+	}
+	if tg.recent.Len() != 0 || tg.flaps.Count() != 0 {
+		t.Error("rolling window or flap detector not reset")
+	}
+	if tg.throughput.count != 0 || len(tg.failures) != 0 || tg.probesSent != 0 || tg.probesLost != 0 {
+		t.Error("throughput, failure tally or packet loss not reset")
+	}
+
+	// The target is down, so a fresh outage starts at the reset
+	if len(tg.incidents) != 1 || tg.incidents[0].Start != now || !tg.incidents[0].ongoing() {
+		t.Errorf("incidents after reset = %+v, want one ongoing from the reset", tg.incidents)
+	}
+	if tg.lastStatus {
+		t.Error("reset changed the current status")
+	}
+	if tg.statusChangeTime != now {
+		t.Errorf("current state starts at %s, want the reset time", tg.statusChangeTime)
+	}
+}
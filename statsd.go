@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsdErrorInterval is how often a failing StatsD server is logged about
+const statsdErrorInterval = time.Minute
+
+// statsdSink sends a latency gauge and a success or failure counter to a
+// StatsD server over UDP after every check
+type statsdSink struct {
+	conn      net.Conn
+	dogstatsd bool // tag metrics with the target instead of naming them after it
+
+	lastError time.Time
+	dropped   int // errors not logged since lastError
+}
+
+func openStatsd(addr string, dogstatsd bool) (*statsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{conn: conn, dogstatsd: dogstatsd}, nil
+}
+
+// Write sends the metrics for a check. Sending is fire-and-forget, errors
+// are logged at most once every statsdErrorInterval so a dead server
+// doesn't flood the log, and never returned.
+func (s *statsdSink) Write(url string, r checkResult) error {
+	if _, err := s.conn.Write([]byte(statsdPacket(url, r, s.dogstatsd))); err != nil {
+		if now := time.Now(); now.Sub(s.lastError) >= statsdErrorInterval {
+			slog.Error("statsd send failed", "error", err, "suppressed", s.dropped)
+			s.lastError, s.dropped = now, 0
+		} else {
+			s.dropped++
+		}
+	}
+	return nil
+}
+
+func (s *statsdSink) Close() error {
+	return s.conn.Close()
+}
+
+// statsdPacket formats the metrics of a check as newline-separated StatsD
+// lines, e.g.
+//
+//	networkcheck.www_google_com.latency_ms:42.1|g
+//	networkcheck.www_google_com.success:1|c
+//
+// or with DogStatsD tags
+//
+//	networkcheck.latency_ms:42.1|g|#url:https://www.google.com
+//	networkcheck.success:1|c|#url:https://www.google.com
+func statsdPacket(url string, r checkResult, dogstatsd bool) string {
+	prefix, suffix := "networkcheck."+statsdName(url)+".", ""
+	if dogstatsd {
+		prefix, suffix = "networkcheck.", "|#url:"+statsdTagEscaper.Replace(url)
+	}
+
+	var b strings.Builder
+	if r.Connected {
+		fmt.Fprintf(&b, "%slatency_ms:%s|g%s\n", prefix, strconv.FormatFloat(durationMs(r.Latency), 'f', -1, 64), suffix)
+		fmt.Fprintf(&b, "%ssuccess:1|c%s", prefix, suffix)
+	} else {
+		fmt.Fprintf(&b, "%sfailure:1|c%s", prefix, suffix)
+	}
+	return b.String()
+}
+
+// statsdName turns a target into a metric name segment, e.g.
+// https://www.google.com becomes www_google_com
+func statsdName(url string) string {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	}
+	url = strings.TrimSuffix(url, "/")
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, url)
+}
+
+// statsdTagEscaper replaces the characters DogStatsD uses to separate tags
+// and fields
+var statsdTagEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_")
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsdPacket(t *testing.T) {
+	ok := checkResult{Connected: true, Latency: 12500 * time.Microsecond}
+	failed := checkResult{Reason: "TIMEOUT"}
+	tests := []struct {
+		name      string
+		url       string
+		r         checkResult
+		dogstatsd bool
+		want      string
+	}{
+		{"success", "https://www.google.com/", ok, false, "networkcheck.www_google_com.latency_ms:12.5|g\nnetworkcheck.www_google_com.success:1|c"},
+		{"failure", "https://www.google.com", failed, false, "networkcheck.www_google_com.failure:1|c"},
+		{"dogstatsd success", "https://example.com", ok, true, "networkcheck.latency_ms:12.5|g|#url:https://example.com\nnetworkcheck.success:1|c|#url:https://example.com"},
+		{"dogstatsd escapes the tag", "https://example.com/?a=1,b|c#d", failed, true, "networkcheck.failure:1|c|#url:https://example.com/?a=1_b_c_d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statsdPacket(tt.url, tt.r, tt.dogstatsd); got != tt.want {
+				t.Errorf("statsdPacket() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsdName(t *testing.T) {
+	tests := []struct{ url, want string }{
+		{"https://www.google.com", "www_google_com"},
+		{"https://www.google.com/", "www_google_com"},
+		{"http://example.com:8080/health?x=1", "example_com_8080_health_x_1"},
+		{"1.1.1.1:53", "1_1_1_1_53"},
+		{"my-host.local", "my-host_local"},
+	}
+	for _, tt := range tests {
+		if got := statsdName(tt.url); got != tt.want {
+			t.Errorf("statsdName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
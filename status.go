@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultExpectStatus is the set of HTTP status codes that count as connected
+// unless -expect-status says otherwise
+const defaultExpectStatus = "200-299"
+
+// parseExpectStatus parses a comma-separated list of status codes and
+// inclusive ranges, e.g. "200-299,401,418", into a predicate that reports
+// whether a code is expected
+func parseExpectStatus(spec string) (func(code int) bool, error) {
+	type statusRange struct{ lo, hi int }
+	var ranges []statusRange
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("empty entry in %q", spec)
+		}
+		loText, hiText, isRange := strings.Cut(part, "-")
+		lo, err := parseStatusCode(loText)
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if isRange {
+			if hi, err = parseStatusCode(hiText); err != nil {
+				return nil, err
+			}
+			if hi < lo {
+				return nil, fmt.Errorf("invalid range %q: end is below start", part)
+			}
+		}
+		ranges = append(ranges, statusRange{lo, hi})
+	}
+
+	return func(code int) bool {
+		for _, r := range ranges {
+			if code >= r.lo && code <= r.hi {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// parseStatusCode parses a single three-digit HTTP status code
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("invalid status code %q", s)
+	}
+	return code, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestParseExpectStatus(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []int
+		rejected []int
+	}{
+		{defaultExpectStatus, []int{200, 204, 299}, []int{199, 300, 401, 500}},
+		{"200-299,401,418", []int{200, 250, 401, 418}, []int{300, 400, 402, 500}},
+		{"401", []int{401}, []int{200, 400}},
+		{" 200 , 301-302 ", []int{200, 301, 302}, []int{300, 303}},
+		{"500-500", []int{500}, []int{499, 501}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			isExpected, err := parseExpectStatus(tt.spec)
+			if err != nil {
+				t.Fatalf("parseExpectStatus(%q) error: %v", tt.spec, err)
+			}
+			for _, code := range tt.expected {
+				if !isExpected(code) {
+					t.Errorf("%d is not expected, want expected", code)
+				}
+			}
+			for _, code := range tt.rejected {
+				if isExpected(code) {
+					t.Errorf("%d is expected, want not expected", code)
+				}
+			}
+		})
+	}
+}
+
+func TestParseExpectStatusErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"200,",
+		"abc",
+		"99",
+		"600",
+		"299-200",
+		"200-",
+		"200-abc",
+	} {
+		if _, err := parseExpectStatus(spec); err == nil {
+			t.Errorf("parseExpectStatus(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// sustainedLatency is a run of checks all slower than -sustained-latency
+// that has lasted at least -sustained-duration
+type sustainedLatency struct {
+	URL       string
+	Time      time.Time
+	Latency   time.Duration // of the check that crossed the duration
+	Threshold time.Duration
+	Streak    time.Duration // since the first slow check of the run
+	Unit      string        // -latency-unit String formats latencies in
+}
+
+func (s sustainedLatency) String() string {
+	return fmt.Sprintf("%s latency above %s for %s (now %s)", s.URL, formatLatency(s.Threshold, s.Unit), formatDuration(s.Streak), formatLatency(s.Latency, s.Unit))
+}
+
+// sustainedNotifier is implemented by notifiers that also alert on
+// sustained high latency, on top of state changes
+type sustainedNotifier interface {
+	NotifySustained(s sustainedLatency)
+}
+
+// sustainedStreak tracks the current run of slow checks of a target
+type sustainedStreak struct {
+	since   time.Time // first slow check of the run, zero if there is none
+	alerted bool
+}
+
+// trackSustained folds a check into the target's slow streak. It returns
+// an alert the first time the streak lasts duration, and cleared once a
+// fast check ends a streak that was alerted on. A failed check ends the
+// streak without clearing it, since the outage is reported on its own.
+func (t *target) trackSustained(r checkResult, threshold, duration time.Duration, unit string) (alert sustainedLatency, fire, cleared bool) {
+	s := &t.sustained
+	if !r.Connected {
+		*s = sustainedStreak{}
+		return sustainedLatency{}, false, false
+	}
+	if r.Latency <= threshold {
+		cleared = s.alerted
+		*s = sustainedStreak{}
+		return sustainedLatency{}, false, cleared
+	}
+	if s.since.IsZero() {
+		s.since = r.Time
+	}
+	streak := r.Time.Sub(s.since)
+	if s.alerted || streak < duration {
+		return sustainedLatency{}, false, false
+	}
+	s.alerted = true
+	return sustainedLatency{
+		URL:       t.url,
+		Time:      r.Time,
+		Latency:   r.Latency,
+		Threshold: threshold,
+		Streak:    streak,
+		Unit:      unit,
+	}, true, false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// symbolSet holds the glyphs the display draws with
+type symbolSet struct {
+	ok, warn, fail string // status markers
+
+	// Status text after the ok and fail markers
+	connected, disconnected string
+
+	// Latency trend arrows
+	rising, falling, steady rune
+
+	spark    []rune // sparkline and chart levels, lowest first
+	gap      rune   // a failed check in a sparkline or chart
+	bar      string // one unit of a histogram bar
+	ellipsis string // the end of a shortened cell
+	micro    string // the microseconds unit
+}
+
+var (
+	unicodeSymbols = symbolSet{
+		ok: "✓", warn: "⚠", fail: "✗",
+		connected: "CONNECTED", disconnected: "DISCONNECTED",
+		rising: '↑', falling: '↓', steady: '→',
+		spark: []rune("▁▂▃▄▅▆▇█"), gap: '·', bar: "█", ellipsis: "…", micro: "µs",
+	}
+	asciiSymbols = symbolSet{
+		ok: "[OK]", warn: "[WARN]", fail: "[DOWN]",
+		connected: "CONNECTED", disconnected: "DISCONNECTED",
+		rising: '^', falling: 'v', steady: '=',
+		spark: []rune("_.:-=+*#"), gap: 'x', bar: "#", ellipsis: "~", micro: "us",
+	}
+)
+
+// symbols is the set in use, asciiSymbols with -ascii
+var symbols = unicodeSymbols
+
+// connectedStatus is the status shown for a connected target
+func (s symbolSet) connectedStatus() string {
+	return s.ok + " " + s.connected
+}
+
+// disconnectedStatus is the status shown for a disconnected target
+func (s symbolSet) disconnectedStatus() string {
+	return s.fail + " " + s.disconnected
+}
+
+// asciiLocale makes a best guess at whether the terminal can't show
+// Unicode: the locale is set but isn't UTF-8
+func asciiLocale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
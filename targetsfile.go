@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// readTargetsFile reads a -targets-file: one target per line, blank lines
+// and lines starting with # ignored. Each target is validated for mode and
+// a malformed one is reported with its line number.
+func readTargetsFile(path, mode string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var targets []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := validateTarget(line, mode); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// validateTarget checks that a target suits the check mode: an http(s) URL
+// in http mode, host:port in tcp mode and a host or URL in icmp and dns mode
+func validateTarget(target, mode string) error {
+	switch mode {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("invalid target %q: %w", target, err)
+		}
+	case "icmp", "dns":
+		if hostFromURL(target) == "" {
+			return fmt.Errorf("invalid target %q: no host", target)
+		}
+	default:
+		u, err := url.Parse(target)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", target, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid URL %q: scheme must be http or https", target)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("invalid URL %q: missing host", target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// tcpProber checks connectivity by opening a TCP connection to a host:port
+// and measuring how long the dial takes
+type tcpProber struct {
+	addr      string
+	timeout   time.Duration
+	ipVersion string
+	source    source
+}
+
+func (p *tcpProber) Probe(ctx context.Context) checkResult {
+	start := time.Now()
+	result := checkResult{Time: start}
+	conn, err := dialFamily(ctx, p.source.dialer(p.timeout), "tcp", p.addr, p.ipVersion)
+	if err != nil {
+		result.Err = err
+		result.Reason = failureReason(err)
+		return result
+	}
+	result.Latency = time.Since(start)
+	result.RemoteIP = addrIP(conn.RemoteAddr())
+	result.LocalIP = addrIP(conn.LocalAddr())
+	conn.Close()
+	result.Connected = true
+	return result
+}
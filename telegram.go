@@ -0,0 +1,46 @@
+package main
+
+// defaultTelegramTemplate is the Telegram message text unless
+// -telegram-template overrides it; the indicator emoji is always prepended
+const defaultTelegramTemplate = "{url} is {status} as of {time} (previous state lasted {duration})"
+
+// telegramAPI is the Bot API base URL, the token and method are appended
+const telegramAPI = "https://api.telegram.org/bot"
+
+// telegramNotifier sends state changes to a chat through a Telegram bot
+type telegramNotifier struct {
+	token    string
+	chatID   string
+	template string
+	poster   *poster
+}
+
+// telegramPayload is the body of a Bot API sendMessage call
+type telegramPayload struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+func (n *telegramNotifier) Notify(c stateChange) {
+	emoji := "🔴"
+	switch c.To {
+	case stateUp:
+		emoji = "🟢"
+	case stateDegraded:
+		emoji = "🟡"
+	}
+	text := emoji + " " + expandTemplate(n.template, c)
+	n.poster.post("telegram", telegramAPI+n.token+"/sendMessage", telegramPayload{ChatID: n.chatID, Text: text})
+}
+
+func (n *telegramNotifier) NotifySpike(s latencySpike) {
+	n.poster.post("telegram", telegramAPI+n.token+"/sendMessage", telegramPayload{ChatID: n.chatID, Text: "⚠️ " + s.String()})
+}
+
+func (n *telegramNotifier) NotifySustained(s sustainedLatency) {
+	n.poster.post("telegram", telegramAPI+n.token+"/sendMessage", telegramPayload{ChatID: n.chatID, Text: "⚠️ " + s.String()})
+}
+
+func (n *telegramNotifier) NotifyFlap(f flapAlert) {
+	n.poster.post("telegram", telegramAPI+n.token+"/sendMessage", telegramPayload{ChatID: n.chatID, Text: "⚠️ " + f.String()})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultThroughputInterval replaces the default -interval in
+	// -throughput mode, since every check downloads a payload
+	defaultThroughputInterval = time.Minute
+
+	// defaultDownloadSize caps how much of the payload is downloaded
+	defaultDownloadSize = 10 << 20
+)
+
+// throughputProber measures download speed by fetching a payload and timing
+// how long reading its body takes
+type throughputProber struct {
+	client           *http.Client
+	timeout          time.Duration
+	url              string
+	header           http.Header
+	isExpectedStatus func(code int) bool
+
+	// maxBytes caps the bytes read from the body
+	maxBytes int64
+}
+
+func (p *throughputProber) Probe(ctx context.Context) checkResult {
+	start := time.Now()
+	result := checkResult{Time: start}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	for key, values := range p.header {
+		req.Header[key] = values
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		result.Err = err
+		result.Reason = failureReason(err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+	result.Server = resp.Header.Get("Server")
+	result.ResponseSize = resp.ContentLength
+	if !p.isExpectedStatus(resp.StatusCode) {
+		result.Reason = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return result
+	}
+
+	bodyStart := time.Now()
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, p.maxBytes))
+	elapsed := time.Since(bodyStart)
+	result.Bytes = n
+	if err != nil {
+		result.Err = err
+		result.Reason = failureReason(err)
+		return result
+	}
+	result.Connected = true
+	if elapsed > 0 {
+		result.Mbps = float64(n) * 8 / elapsed.Seconds() / 1e6
+	}
+	return result
+}
+
+// throughputStats aggregates the download speeds of successful checks
+type throughputStats struct {
+	min, max, total float64
+	count           int
+}
+
+func (s *throughputStats) add(mbps float64) {
+	if s.count == 0 || mbps < s.min {
+		s.min = mbps
+	}
+	s.max = max(s.max, mbps)
+	s.total += mbps
+	s.count++
+}
+
+// avg returns the mean download speed in Mbps
+func (s *throughputStats) avg() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.total / float64(s.count)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timestamps are shown in timeLocation, and in the display with timeLayout,
+// as set by -timezone and -timestamp-format at startup
+var (
+	timeLocation = time.Local
+	timeLayout   = "15:04:05"
+)
+
+// setTimezone sets timeLocation from a -timezone value: local, UTC or an
+// IANA zone name such as America/New_York
+func setTimezone(name string) error {
+	if name == "local" {
+		timeLocation = time.Local
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid -timezone %q: %w", name, err)
+	}
+	timeLocation = loc
+	return nil
+}
+
+// clock formats a time for the display
+func clock(t time.Time) string {
+	return t.In(timeLocation).Format(timeLayout)
+}
+
+// stamp formats a time as RFC 3339 for logs and machine-readable output
+func stamp(t time.Time) string {
+	return t.In(timeLocation).Format(time.RFC3339)
+}
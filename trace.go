@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// phaseTimings breaks an HTTP check's latency down into its phases. On a
+// connection reused from keep-alive the DNS, connect and TLS phases don't
+// happen and stay zero.
+type phaseTimings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration // from the start of the request to the first response byte
+	Reused  bool
+}
+
+// phaseTracer collects phaseTimings through httptrace hooks, which may be
+// called from the transport's own goroutines
+type phaseTracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      phaseTimings
+}
+
+func newPhaseTracer(start time.Time) *phaseTracer {
+	return &phaseTracer{start: start}
+}
+
+// clientTrace returns the hooks to attach to the request's context
+func (p *phaseTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.mu.Lock()
+			p.dnsStart = time.Now()
+			p.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.mu.Lock()
+			p.timings.DNS = time.Since(p.dnsStart)
+			p.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			p.mu.Lock()
+			// Dual-stack dialing may start several attempts, time from the first
+			if p.connectStart.IsZero() {
+				p.connectStart = time.Now()
+			}
+			p.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			p.mu.Lock()
+			if err == nil && p.timings.Connect == 0 {
+				p.timings.Connect = time.Since(p.connectStart)
+			}
+			p.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			p.mu.Lock()
+			p.tlsStart = time.Now()
+			p.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.mu.Lock()
+			p.timings.TLS = time.Since(p.tlsStart)
+			p.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			p.timings.Reused = info.Reused
+			p.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			p.mu.Lock()
+			p.timings.TTFB = time.Since(p.start)
+			p.mu.Unlock()
+		},
+	}
+}
+
+// result returns the timings collected so far
+func (p *phaseTracer) result() *phaseTimings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timings := p.timings
+	return &timings
+}
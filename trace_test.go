@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestProber returns an httpProber for url expecting a 2xx status
+func newTestProber(client *http.Client, url string) *httpProber {
+	isExpected, _ := parseExpectStatus(defaultExpectStatus)
+	return &httpProber{
+		client:           client,
+		timeout:          5 * time.Second,
+		method:           http.MethodGet,
+		url:              url,
+		isExpectedStatus: isExpected,
+		maxBodyRead:      4096,
+	}
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+func TestTracePhasesFreshConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer srv.Close()
+
+	// Through localhost rather than the server's IP so there is a lookup
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	p := newTestProber(&http.Client{Transport: &http.Transport{}}, url)
+	p.trace = true
+
+	r := p.Probe(context.Background())
+	if !r.Connected {
+		t.Fatalf("check failed: %v", r.Err)
+	}
+	ph := r.Phases
+	if ph == nil {
+		t.Fatal("Phases is nil on a traced check")
+	}
+	if ph.Reused {
+		t.Error("first connection reported as reused")
+	}
+	if ph.DNS <= 0 || ph.Connect <= 0 || ph.TTFB <= 0 {
+		t.Errorf("phases not all populated: %+v", *ph)
+	}
+	if ph.TLS != 0 {
+		t.Errorf("TLS = %s for plain HTTP, want 0", ph.TLS)
+	}
+	if ph.TTFB > r.Latency {
+		t.Errorf("TTFB %s is longer than the latency %s", ph.TTFB, r.Latency)
+	}
+}
+
+func TestTracePhasesTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer srv.Close()
+
+	p := newTestProber(srv.Client(), srv.URL)
+	p.trace = true
+	r := p.Probe(context.Background())
+	if !r.Connected {
+		t.Fatalf("check failed: %v", r.Err)
+	}
+	if r.Phases == nil || r.Phases.TLS <= 0 || r.Phases.Connect <= 0 {
+		t.Errorf("phases = %+v, want connect and TLS populated", r.Phases)
+	}
+}
+
+func TestTracePhasesReusedConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer srv.Close()
+
+	p := newTestProber(&http.Client{Transport: &http.Transport{}}, srv.URL)
+	p.trace = true
+	p.Probe(context.Background())
+	r := p.Probe(context.Background())
+	if !r.Connected {
+		t.Fatalf("check failed: %v", r.Err)
+	}
+	ph := r.Phases
+	if ph == nil || !ph.Reused {
+		t.Fatalf("phases = %+v, want a reused connection", ph)
+	}
+	if ph.DNS != 0 || ph.Connect != 0 || ph.TLS != 0 {
+		t.Errorf("reused connection has setup phases: %+v", *ph)
+	}
+	if ph.TTFB <= 0 {
+		t.Errorf("TTFB = %s on a reused connection, want it populated", ph.TTFB)
+	}
+}
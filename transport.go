@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// transportConfig holds the options that shape the HTTP transport used for
+// checks
+type transportConfig struct {
+	// insecure skips TLS certificate verification
+	insecure bool
+
+	// clientCerts are presented to servers that ask for a client
+	// certificate, for mutual TLS
+	clientCerts []tls.Certificate
+
+	// rootCAs verifies server certificates instead of the system roots,
+	// nil uses the system roots
+	rootCAs *x509.CertPool
+
+	// proxy routes all checks through this proxy, nil falls back to the
+	// HTTP_PROXY/HTTPS_PROXY environment variables
+	proxy *url.URL
+
+	// ipVersion restricts connections to IPv4 ("4") or IPv6 ("6")
+	ipVersion string
+
+	// source binds connections to a local interface or address
+	source source
+
+	// noKeepAlive opens a fresh connection for every check, so each one
+	// pays for DNS, TCP and TLS setup and the phase timings stay comparable
+	noKeepAlive bool
+
+	// pins, when set, dials each pinned host at its pinned address
+	pins *pinnedHosts
+}
+
+// newTransport builds an HTTP transport from Go's defaults with cfg applied
+func newTransport(cfg transportConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = cfg.noKeepAlive
+	if cfg.insecure || cfg.clientCerts != nil || cfg.rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: cfg.insecure,
+			Certificates:       cfg.clientCerts,
+			RootCAs:            cfg.rootCAs,
+		}
+	}
+	if cfg.ipVersion != "" || cfg.source.ip != nil || cfg.source.device != "" || cfg.pins != nil {
+		dialer := cfg.source.dialer(30 * time.Second)
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if cfg.pins != nil {
+				addr = cfg.pins.addr(addr)
+			}
+			return dialFamily(ctx, dialer, network, addr, cfg.ipVersion)
+		}
+	}
+	if cfg.proxy != nil {
+		transport.Proxy = http.ProxyURL(cfg.proxy)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return transport
+}
+
+// loadCAPool reads the PEM certificates in path for -ca-cert
+func loadCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// parseProxyURL validates a -proxy value
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q in %q", u.Scheme, raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in %q", raw)
+	}
+	return u, nil
+}
+
+// describeProxy reports which proxy, if any, a check of target goes through,
+// with any proxy credentials redacted
+func describeProxy(transport *http.Transport, target string) string {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return "none"
+	}
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil {
+		return "none"
+	}
+	return proxy.Redacted()
+}
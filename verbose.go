@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// verboseHeaders are the response headers shown by -verbose
+var verboseHeaders = []string{"Server", "Content-Type", "Content-Length"}
+
+// verboseReport describes an HTTP check for -verbose: the request, the
+// response status line and selected headers, the server address and, on
+// failure, every error in the chain. resp is nil when no response arrived.
+func verboseReport(req *http.Request, resp *http.Response, r checkResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] > %s %s\n", clock(r.Time), req.Method, req.URL)
+	if resp != nil {
+		fmt.Fprintf(&b, "  < %s %s\n", resp.Proto, resp.Status)
+		for _, key := range verboseHeaders {
+			if value := resp.Header.Get(key); value != "" {
+				fmt.Fprintf(&b, "  < %s: %s\n", key, value)
+			}
+		}
+	}
+	if resp != nil && r.ResponseSize >= 0 {
+		fmt.Fprintf(&b, "  body %d bytes\n", r.ResponseSize)
+	}
+	if r.RemoteIP != nil {
+		fmt.Fprintf(&b, "  remote %s\n", remote(r.RemoteIP))
+	}
+	if r.LocalIP != nil {
+		fmt.Fprintf(&b, "  local %s\n", r.LocalIP)
+	}
+	if p := r.Phases; p != nil && resp != nil {
+		fmt.Fprintf(&b, "  dns %s, connect %s, tls %s, ttfb %s\n", p.DNS, p.Connect, p.TLS, p.TTFB)
+	}
+	for err, prefix := r.Err, "error"; err != nil; err, prefix = errors.Unwrap(err), "caused by" {
+		fmt.Fprintf(&b, "  %s: %v\n", prefix, err)
+	}
+	return b.String()
+}
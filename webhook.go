@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds each delivery attempt independently of the
+	// probe timeout
+	webhookTimeout = 5 * time.Second
+
+	// maxPendingPosts caps deliveries in flight at once; alerts beyond it
+	// are dropped rather than piling up goroutines while flapping
+	maxPendingPosts = 8
+)
+
+// poster delivers JSON payloads in the background, shared by every
+// notifier that talks HTTP
+type poster struct {
+	client   *http.Client
+	pending  chan struct{}
+	inFlight sync.WaitGroup
+}
+
+func newPoster() *poster {
+	return &poster{
+		client:  &http.Client{Timeout: webhookTimeout},
+		pending: make(chan struct{}, maxPendingPosts),
+	}
+}
+
+// post sends payload as JSON to the given URL without blocking, retrying
+// once on failure and logging the outcome under name
+func (p *poster) post(name, to string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("cannot encode payload", "notifier", name, "error", err)
+		return
+	}
+
+	select {
+	case p.pending <- struct{}{}:
+	default:
+		slog.Warn("too many deliveries pending, dropping alert", "notifier", name)
+		return
+	}
+	p.inFlight.Add(1)
+	go func() {
+		defer p.inFlight.Done()
+		defer func() { <-p.pending }()
+		err := p.send(to, body)
+		if err != nil {
+			err = p.send(to, body)
+		}
+		if err != nil {
+			// Leave out the URL, it may hold a secret such as a bot token
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			slog.Error("delivery failed", "notifier", name, "error", err)
+			return
+		}
+		slog.Log(context.Background(), levelNotice, "delivered", "notifier", name)
+	}()
+}
+
+// wait blocks until every delivery has finished or timeout has passed,
+// reporting whether they all finished
+func (p *poster) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		p.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// send makes a single delivery attempt
+func (p *poster) send(url string, body []byte) error {
+	resp, err := p.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookPayload is the JSON body posted to -webhook on state changes
+type webhookPayload struct {
+	URL       string `json:"url"`
+	Status    string `json:"status"` // "up", "down", "degraded", "spike", "sustained_latency" or "flapping"
+	Timestamp string `json:"timestamp"`
+
+	// Seconds spent in the state just left, only one of them is set
+	DowntimeDuration float64 `json:"downtime_duration,omitempty"`
+	UptimeDuration   float64 `json:"uptime_duration,omitempty"`
+	DegradedDuration float64 `json:"degraded_duration,omitempty"`
+
+	// Message describes a recovery, e.g. "Recovered after 3m 12s", or a
+	// latency spike
+	Message string `json:"message,omitempty"`
+}
+
+// webhookNotifier posts a generic JSON payload to a URL on state changes
+type webhookNotifier struct {
+	url    string
+	poster *poster
+}
+
+func (n *webhookNotifier) Notify(c stateChange) {
+	n.poster.post("webhook", n.url, newWebhookPayload(c))
+}
+
+// newWebhookPayload describes a state change
+func newWebhookPayload(c stateChange) webhookPayload {
+	payload := webhookPayload{
+		URL:       c.URL,
+		Status:    c.To.String(),
+		Timestamp: stamp(c.Time),
+	}
+	switch c.From {
+	case stateDown:
+		payload.DowntimeDuration = c.Previous.Seconds()
+		if c.recovered() {
+			payload.Message = "Recovered after " + formatDuration(c.Previous)
+		}
+	case stateUp:
+		payload.UptimeDuration = c.Previous.Seconds()
+	case stateDegraded:
+		payload.DegradedDuration = c.Previous.Seconds()
+	}
+	return payload
+}
+
+func (n *webhookNotifier) NotifySpike(s latencySpike) {
+	n.poster.post("webhook", n.url, newSpikePayload(s))
+}
+
+func (n *webhookNotifier) NotifySustained(s sustainedLatency) {
+	n.poster.post("webhook", n.url, newSustainedPayload(s))
+}
+
+func (n *webhookNotifier) NotifyFlap(f flapAlert) {
+	n.poster.post("webhook", n.url, newFlapPayload(f))
+}
+
+// newSpikePayload describes a latency spike
+func newSpikePayload(s latencySpike) webhookPayload {
+	return webhookPayload{
+		URL:       s.URL,
+		Status:    "spike",
+		Timestamp: stamp(s.Time),
+		Message:   s.String(),
+	}
+}
+
+// newSustainedPayload describes sustained high latency
+func newSustainedPayload(s sustainedLatency) webhookPayload {
+	return webhookPayload{
+		URL:       s.URL,
+		Status:    "sustained_latency",
+		Timestamp: stamp(s.Time),
+		Message:   s.String(),
+	}
+}
+
+// newFlapPayload describes a target starting to flap
+func newFlapPayload(f flapAlert) webhookPayload {
+	return webhookPayload{
+		URL:       f.URL,
+		Status:    "flapping",
+		Timestamp: stamp(f.Time),
+		Message:   f.String(),
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "time"
+
+// window is a fixed-size ring buffer of the most recent check outcomes
+type window struct {
+	results []windowEntry
+	next    int // index the next result is written to
+	count   int // number of results held, up to len(results)
+}
+
+// windowEntry is a single check outcome held by a window
+type windowEntry struct {
+	ok      bool
+	latency time.Duration
+}
+
+func newWindow(size int) *window {
+	return &window{results: make([]windowEntry, size)}
+}
+
+// Push records a check outcome, evicting the oldest once the window is full
+func (w *window) Push(ok bool, latency time.Duration) {
+	w.results[w.next] = windowEntry{ok: ok, latency: latency}
+	w.next = (w.next + 1) % len(w.results)
+	if w.count < len(w.results) {
+		w.count++
+	}
+}
+
+// Reset discards every outcome held
+func (w *window) Reset() {
+	clear(w.results)
+	w.next, w.count = 0, 0
+}
+
+// Len returns the number of outcomes currently held
+func (w *window) Len() int {
+	return w.count
+}
+
+// Entries returns the outcomes held, oldest first
+func (w *window) Entries() []windowEntry {
+	entries := make([]windowEntry, 0, w.count)
+	start := (w.next - w.count + len(w.results)) % len(w.results)
+	for i := 0; i < w.count; i++ {
+		entries = append(entries, w.results[(start+i)%len(w.results)])
+	}
+	return entries
+}
+
+// SuccessRate returns the percentage of successful outcomes in the window,
+// and false if it is still empty
+func (w *window) SuccessRate() (float64, bool) {
+	if w.count == 0 {
+		return 0, false
+	}
+	ok := 0
+	for _, r := range w.results[:w.count] {
+		if r.ok {
+			ok++
+		}
+	}
+	return float64(ok) / float64(w.count) * 100, true
+}
+
+// LatencyStats returns the mean and spread of the latencies of the
+// successful outcomes held
+func (w *window) LatencyStats() welford {
+	var stats welford
+	for _, r := range w.results[:w.count] {
+		if r.ok {
+			stats.add(float64(r.latency))
+		}
+	}
+	return stats
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWindowSuccessRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		pushes  []bool
+		want    float64
+		wantLen int
+	}{
+		{"partly filled", 4, []bool{true, false}, 50, 2},
+		{"exactly full", 4, []bool{true, true, true, false}, 75, 4},
+		{"wraps once", 4, []bool{false, false, true, true, true, true}, 100, 4},
+		{"wraps and evicts successes", 3, []bool{true, true, true, false, false}, 100.0 / 3, 3},
+		{"wraps several times", 2, []bool{true, false, true, false, true, false, false}, 0, 2},
+		{"size one", 1, []bool{false, true}, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWindow(tt.size)
+			for _, ok := range tt.pushes {
+				w.Push(ok, time.Millisecond)
+			}
+			got, ok := w.SuccessRate()
+			if !ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("SuccessRate() = %g, %v, want %g", got, ok, tt.want)
+			}
+			if w.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", w.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestWindowEmpty(t *testing.T) {
+	w := newWindow(3)
+	if _, ok := w.SuccessRate(); ok {
+		t.Error("SuccessRate() of an empty window reported a rate")
+	}
+	w.Push(true, 0)
+	w.Reset()
+	if _, ok := w.SuccessRate(); ok || w.Len() != 0 {
+		t.Error("window isn't empty after Reset")
+	}
+}
+
+func TestWindowEntriesOldestFirst(t *testing.T) {
+	w := newWindow(3)
+	for i := 1; i <= 5; i++ {
+		w.Push(true, time.Duration(i))
+	}
+	entries := w.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	for i, want := range []time.Duration{3, 4, 5} {
+		if entries[i].latency != want {
+			t.Errorf("entry %d latency = %d, want %d", i, entries[i].latency, want)
+		}
+	}
+}
+
+func TestWindowLatencyStats(t *testing.T) {
+	w := newWindow(3)
+	w.Push(true, 100)
+	w.Push(false, 0)
+	w.Push(true, 200)
+	w.Push(true, 400) // evicts 100
+	stats := w.LatencyStats()
+	if stats.n != 2 || stats.mean != 300 || stats.stddev() != 100 {
+		t.Errorf("LatencyStats() = n %d mean %g stddev %g, want n 2 mean 300 stddev 100", stats.n, stats.mean, stats.stddev())
+	}
+}